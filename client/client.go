@@ -1,104 +1,348 @@
 package client
 
 import (
-	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"os/user"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 	"github.com/vilmibm/hermeticum/proto"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
+// ConnectOpts is read from the client config file (see LoadConnectOpts) and
+// then overridden by command line flags.
 type ConnectOpts struct {
+	// Address is the server's unix socket path or any other address grpc can dial.
+	Address string `toml:"address"`
+	// Theme is the path to a theme file; see Theme.
+	Theme string `toml:"theme"`
+	// Scrollback is how many messages are kept for scrolling and /find.
+	Scrollback int `toml:"scrollback"`
+	// TranscriptDir, if set, is where a timestamped log of each session is written.
+	TranscriptDir string `toml:"transcript"`
+	// Keys maps actions like "history_prev" to key names like "Up".
+	Keys map[string]string `toml:"keys"`
+	// Aliases and Triggers are managed with /alias and /trigger, which save
+	// them back to ConfigPath.
+	Aliases    map[string]string `toml:"aliases"`
+	Triggers   []*Trigger        `toml:"triggers"`
+	ConfigPath string            `toml:"-"`
 }
 
 type ClientState struct {
 	App          *tview.Application
-	Client       proto.GameWorldClient
+	Session      *Session
 	MaxMessages  int
 	messagesView *tview.TextView
-	events       []*proto.WorldEvent
 	cio          *clientIO
+	// everything below is only touched from the tview event loop
+	room       *proto.RoomState
+	history    *history
+	multiline  []string
+	scrollback *scrollback
+	// shown is how many events the messages view holds, which can be a few
+	// more than the scrollback between redraws
+	shown int
+	// following is whether the messages view sticks to the newest message
+	following bool
+	findTerm  string
+	// findAt is the position of the current match among every event added to
+	// the scrollback, not just those still in it
+	findAt     int
+	transcript io.WriteCloser
+	theme      *Theme
+	// self is the user's name, which is also their avatar's name
+	self   string
+	screen tcell.Screen
+	keys   map[tcell.Key]string
+
+	aliases    map[string]string
+	triggers   []*Trigger
+	configPath string
 }
 
 func (cs *ClientState) HandleInput(input string) {
-	var verb string
-	rest := input
-	if strings.HasPrefix(input, "/") {
-		verb, rest, _ = strings.Cut(input[1:], " ")
-	} else {
-		verb = "say"
-	}
-	cmd := &proto.Command{
-		Verb: verb,
-		Rest: rest,
+	if cmds, ok := expandAlias(cs.aliases, input); ok {
+		for _, cmd := range cmds {
+			cs.runInput(cmd)
+		}
+		return
 	}
-	cs.cio.outbound <- cmd
+
+	cs.runInput(input)
 }
 
-func (cs *ClientState) AddMessage(ev *proto.WorldEvent) {
-	// TODO i don't like this function
-	cs.events = append(cs.events, ev)
-	if len(cs.events) > cs.MaxMessages {
-		cs.events = cs.events[1 : len(cs.events)-1]
+// runInput handles the verbs that never leave the client and sends everything
+// else to the server.
+func (cs *ClientState) runInput(input string) {
+	cmd := ParseInput(input)
+
+	switch cmd.Verb {
+	case "find":
+		cs.find(cmd.Rest)
+	case "alias":
+		cs.handleAlias(cmd.Rest)
+	case "unalias":
+		cs.handleUnalias(cmd.Rest)
+	case "trigger":
+		cs.handleTrigger(cmd.Rest)
+	case "untrigger":
+		cs.handleUntrigger(cmd.Rest)
+	default:
+		cs.cio.outbound <- cmd
 	}
+}
 
+func (cs *ClientState) AddMessage(ev *proto.WorldEvent) {
 	// TODO look into using the SetChangedFunc thing.
 	cs.App.QueueUpdateDraw(func() {
-		// TODO trim content of messagesView /or/ see if tview has a buffer size that does it for me. use cs.messages to re-constitute.
-		switch ev.Type {
-		case proto.WorldEvent_OVERHEARD:
-			fmt.Fprintf(cs.messagesView, "%s: %s\n", ev.GetSource(), ev.GetText())
-		case proto.WorldEvent_EMOTE:
-			fmt.Fprintf(cs.messagesView, "%s %s\n", ev.GetSource(), ev.GetText())
-		case proto.WorldEvent_PRINT:
-			fmt.Fprintf(cs.messagesView, "%s\n", ev.GetText())
-		default:
-			fmt.Fprintf(cs.messagesView, "%#v\n", ev)
+		if ev.Type == proto.WorldEvent_ROOM {
+			cs.room = ev.GetRoom()
+			return
 		}
-		cs.messagesView.ScrollToEnd()
+		cs.record(ev)
+		cs.fireTriggers(ev)
 	})
-	/*
-		for _, ev := range cs.events {
-			fmt.Print("\x1b[1B")
-			switch ev.Type {
-			case proto.WorldEvent_OVERHEARD:
-				fmt.Printf("%s: %s\n", ev.GetSource(), ev.GetText())
-			case proto.WorldEvent_EMOTE:
-				fmt.Printf("%s %s\n", ev.GetSource(), ev.GetText())
-			default:
-				fmt.Printf("%#v\n", ev)
-			}
+}
+
+// render draws ev for the messages view, letting highlight triggers override the theme.
+func (cs *ClientState) render(ev *proto.WorldEvent) string {
+	if style := cs.highlightFor(ev); style != "" {
+		p := painter{theme: cs.theme, base: style}
+		return p.line(tview.Escape(eventText(ev)))
+	}
+
+	return renderEvent(ev, cs.theme, cs.self)
+}
+
+// printLocal shows msg as though the server had sent it.
+func (cs *ClientState) printLocal(msg string) {
+	cs.record(&proto.WorldEvent{
+		Type: proto.WorldEvent_PRINT,
+		Text: &msg,
+	})
+}
+
+// record adds ev to the scrollback, the messages view and the transcript.
+func (cs *ClientState) record(ev *proto.WorldEvent) {
+	cs.writeTranscript(ev)
+
+	if cs.scrollback.Add(ev) && cs.findTerm != "" && cs.findAt < cs.scrollback.Offset() {
+		// the match has scrolled away
+		cs.findTerm = ""
+		cs.messagesView.Highlight()
+	}
+
+	// rebuilding the view on every message once the scrollback is full would
+	// be slow, so let it grow by half again first
+	cs.shown++
+	if cs.shown > cs.scrollback.Size()+cs.scrollback.Size()/2 {
+		cs.redraw()
+	} else {
+		fmt.Fprintln(cs.messagesView, cs.render(ev))
+	}
+
+	if ev.Type == proto.WorldEvent_WHISPER && cs.theme.Bell && cs.screen != nil {
+		cs.screen.Beep()
+	}
+
+	if cs.following {
+		cs.messagesView.ScrollToEnd()
+	}
+}
+
+// redraw rebuilds the messages view from the scrollback.
+func (cs *ClientState) redraw() {
+	w := cs.messagesView.BatchWriter()
+	defer w.Close()
+	w.Clear()
+
+	evs := cs.scrollback.Events()
+	for ix, ev := range evs {
+		at := cs.scrollback.Offset() + ix
+		text := cs.render(ev)
+		if cs.findTerm != "" && at == cs.findAt {
+			text = fmt.Sprintf(`["%d"]%s[""]`, at, text)
+		}
+		fmt.Fprintln(w, text)
+	}
+	cs.shown = len(evs)
+
+	if cs.findTerm != "" {
+		cs.messagesView.Highlight(strconv.Itoa(cs.findAt))
+	}
+}
+
+// find highlights the newest message containing term. Searching for the same
+// term again moves on to the next oldest match; an empty term clears the search.
+func (cs *ClientState) find(term string) {
+	term = strings.TrimSpace(term)
+	if term == "" {
+		cs.findTerm = ""
+		cs.messagesView.Highlight()
+		cs.redraw()
+		cs.following = true
+		cs.messagesView.ScrollToEnd()
+		return
+	}
+
+	evs := cs.scrollback.Events()
+	from := len(evs)
+	if term == cs.findTerm {
+		from = max(cs.findAt-cs.scrollback.Offset(), 0)
+	}
+
+	for ix := from - 1; ix >= 0; ix-- {
+		if strings.Contains(strings.ToLower(eventText(evs[ix])), strings.ToLower(term)) {
+			cs.findTerm = term
+			cs.findAt = cs.scrollback.Offset() + ix
+			cs.following = false
+			cs.redraw()
+			cs.messagesView.ScrollToHighlight()
+			return
+		}
+	}
+
+	cs.printLocal(fmt.Sprintf("no more messages containing '%s'. use /find with nothing after it to stop searching.", term))
+}
+
+func (cs *ClientState) writeTranscript(ev *proto.WorldEvent) {
+	if cs.transcript == nil {
+		return
+	}
+
+	stamp := time.Now().Format("2006-01-02 15:04:05")
+	for _, line := range strings.Split(eventText(ev), "\n") {
+		fmt.Fprintf(cs.transcript, "%s %s\n", stamp, line)
+	}
+}
+
+// openTranscript creates a new transcript file in dir named for when the session started.
+func openTranscript(dir string) (io.WriteCloser, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("hermeticum-%s.log", time.Now().Format("2006-01-02T15-04-05"))
+
+	return os.OpenFile(filepath.Join(dir, name), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+}
+
+// scroll moves the messages view by a page. Scrolling back to the bottom
+// resumes following new messages.
+func (cs *ClientState) scroll(pages int) {
+	_, _, _, height := cs.messagesView.GetInnerRect()
+	row, _ := cs.messagesView.GetScrollOffset()
+	row += pages * height
+
+	if row < 0 {
+		row = 0
+	}
+
+	if row >= cs.messagesView.GetOriginalLineCount()-height {
+		cs.following = true
+		cs.messagesView.ScrollToEnd()
+		return
+	}
+
+	cs.following = false
+	cs.messagesView.ScrollTo(row, 0)
+}
+
+// verbs returns what can be tab completed after a slash. The server sends the
+// builtin verbs it will let this user use along with each room.
+func (cs *ClientState) verbs() []string {
+	return append(append([]string{}, localVerbs...), cs.room.GetVerbs()...)
+}
+
+// nouns returns what can be tab completed after a verb.
+func (cs *ClientState) nouns() []string {
+	out := append([]string{}, directions...)
+	for _, o := range cs.room.GetContents() {
+		out = append(out, o.GetName())
+	}
+
+	return out
+}
+
+// handleKey implements history browsing, tab completion and multi-line input
+// on top of the editing keys InputField already supports.
+func (cs *ClientState) handleKey(input *tview.InputField, ev *tcell.EventKey) *tcell.EventKey {
+	if ev.Key() == tcell.KeyRune {
+		return ev
+	}
+
+	switch cs.keys[ev.Key()] {
+	case actionHistoryPrev:
+		if line, ok := cs.history.Prev(input.GetText()); ok {
+			input.SetText(line)
+		}
+	case actionHistoryNext:
+		if line, ok := cs.history.Next(); ok {
+			input.SetText(line)
+		}
+	case actionComplete:
+		candidates := complete(input.GetText(), cs.verbs(), cs.nouns())
+		switch len(candidates) {
+		case 0:
+		case 1:
+			input.SetText(candidates[0])
+		default:
+			input.SetText(commonPrefix(candidates))
+			cs.printLocal(strings.Join(candidates, "  "))
+		}
+	case actionPageUp:
+		cs.scroll(-1)
+	case actionPageDown:
+		cs.scroll(1)
+	case actionNewline:
+		// start a new line; enter sends them all.
+		cs.multiline = append(cs.multiline, input.GetText())
+		input.SetText("")
+		input.SetLabel(fmt.Sprintf("%d> ", len(cs.multiline)+1))
+	case actionCancel:
+		if len(cs.multiline) == 0 {
+			return ev
 		}
-	*/
+		cs.multiline = nil
+		input.SetText("")
+		input.SetLabel("> ")
+	default:
+		return ev
+	}
+
+	return nil
 }
 
 type clientIO struct {
-	inbound  chan *proto.WorldEvent
 	outbound chan *proto.Command
 	errs     chan error
 	done     chan bool
 }
 
 func Connect(opts ConnectOpts) error {
-	gc, err := grpc.NewClient(
-		"unix:///tmp/hermeticum.sock",
-		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	keys, err := parseKeys(opts.Keys)
 	if err != nil {
 		return err
 	}
-	client := proto.NewGameWorldClient(gc)
+
+	sess, err := Dial(opts.Address)
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+
 	app := tview.NewApplication()
 
 	cio := &clientIO{
-		inbound:  make(chan *proto.WorldEvent),
 		outbound: make(chan *proto.Command),
 		errs:     make(chan error, 1),
 		done:     make(chan bool, 1),
@@ -106,36 +350,69 @@ func Connect(opts ConnectOpts) error {
 
 	// TODO make a NewClientState
 	// TODO rename this, like, UI
+	hpath, err := historyPath()
+	if err != nil {
+		log.Printf("not saving command history: %s", err.Error())
+	}
+
+	theme, err := LoadTheme(opts.Theme)
+	if err != nil {
+		return err
+	}
+
+	self := ""
+	if u, err := user.Current(); err == nil {
+		self = u.Username
+	}
+
 	cs := &ClientState{
 		App:         app,
-		Client:      client,
-		MaxMessages: 15, // TODO for testing
-		events:      []*proto.WorldEvent{},
+		Session:     sess,
+		MaxMessages: opts.Scrollback,
 		cio:         cio,
+		history:     loadHistory(hpath),
+		following:   true,
+		theme:       theme,
+		self:        self,
+		keys:        keys,
+		aliases:     opts.Aliases,
+		triggers:    opts.Triggers,
+		configPath:  opts.ConfigPath,
 	}
+	cs.scrollback = newScrollback(cs.MaxMessages)
 
-	now := fmt.Sprintf("%d", time.Now().Unix())
-
-	if _, err = cs.Client.Ping(context.Background(), &proto.PingMsg{When: now}); err != nil {
-		log.Fatalf("%v.Ping -> %v", cs.Client, err)
+	if opts.TranscriptDir != "" {
+		if cs.transcript, err = openTranscript(opts.TranscriptDir); err != nil {
+			return fmt.Errorf("could not open transcript: %w", err)
+		}
+		defer cs.transcript.Close()
 	}
 
 	commandInput := tview.NewInputField().SetLabel("> ")
-	handleInput := func(_ tcell.Key) {
-		input := commandInput.GetText()
-		// TODO command history
+	handleInput := func(key tcell.Key) {
+		if key != tcell.KeyEnter {
+			return
+		}
+		input := strings.Join(append(cs.multiline, commandInput.GetText()), "\n")
+		cs.multiline = nil
+		commandInput.SetLabel("> ")
+		cs.history.Add(input)
 		commandInput.SetText("")
 		// TODO do i need to clear the input's text?
 		cs.HandleInput(input)
 	}
 
 	commandInput.SetDoneFunc(handleInput)
+	commandInput.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		return cs.handleKey(commandInput, ev)
+	})
 
 	// TODO need to hit ctrl c twice to quit but otherwise quitting works how i want
 	sigC := make(chan os.Signal, 1)
 	signal.Notify(sigC, os.Interrupt)
 
-	msgView := tview.NewTextView().SetScrollable(true).SetWrap(true).SetWordWrap(true)
+	msgView := tview.NewTextView().SetScrollable(true).SetWrap(true).SetWordWrap(true).
+		SetRegions(true).SetDynamicColors(true)
 	cs.messagesView = msgView
 	gamePage := tview.NewGrid().
 		SetRows(1, 40, 3).
@@ -160,23 +437,11 @@ func Connect(opts ConnectOpts) error {
 	pages := tview.NewPages()
 	pages.AddPage("game", gamePage, true, true)
 
-	ctx := context.Background()
-
-	stream, err := cs.Client.ClientInput(ctx)
-	if err != nil {
-		return fmt.Errorf("could not create command stream: %w", err)
-	}
-
-	go func() {
-		for {
-			if ev, err := stream.Recv(); err != nil {
-				cio.errs <- err
-				cio.done <- true
-			} else {
-				cio.inbound <- ev
-			}
-		}
-	}()
+	app.SetBeforeDrawFunc(func(screen tcell.Screen) bool {
+		// held on to so whispers can ring the bell
+		cs.screen = screen
+		return false
+	})
 
 	go func() {
 		err := app.SetRoot(pages, true).SetFocus(commandInput).Run()
@@ -186,23 +451,6 @@ func Connect(opts ConnectOpts) error {
 		}
 	}()
 
-	/*
-		go func() {
-			for {
-				var s string
-				r := bufio.NewReader(os.Stdin)
-				for {
-					fmt.Fprint(os.Stdout, "\x1b[H> ")
-					s, _ = r.ReadString('\n')
-					if s != "" {
-						break
-					}
-				}
-				cs.HandleInput(strings.TrimSpace(s))
-			}
-		}()
-	*/
-
 	go func() {
 		for range sigC {
 			cmd := &proto.Command{
@@ -214,10 +462,17 @@ func Connect(opts ConnectOpts) error {
 
 	for {
 		select {
-		case ev := <-cio.inbound:
+		case ev, ok := <-sess.Events():
+			if !ok {
+				if err := sess.Err(); err != nil {
+					log.Printf("error: %s", err.Error())
+				}
+				cs.App.Stop()
+				return nil
+			}
 			cs.AddMessage(ev)
 		case cmd := <-cio.outbound:
-			if err := stream.Send(cmd); err != nil {
+			if err := sess.Send(cmd); err != nil {
 				cio.errs <- err
 			}
 			if cmd.Verb == "quit" {
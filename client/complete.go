@@ -0,0 +1,93 @@
+package client
+
+import (
+	"sort"
+	"strings"
+)
+
+// localVerbs never leave the client.
+var localVerbs = []string{
+	"alias",
+	"find",
+	"trigger",
+	"unalias",
+	"untrigger",
+}
+
+var directions = []string{
+	"above",
+	"below",
+	"down",
+	"east",
+	"north",
+	"south",
+	"up",
+	"west",
+}
+
+// complete returns every way the end of text could be finished. Each
+// candidate is the full line as it would appear after completion. The first
+// word of a slash command is completed against verbs; anything later is
+// completed against nouns (the names of things in the room, directions).
+func complete(text string, verbs, nouns []string) []string {
+	out := []string{}
+	seen := map[string]bool{}
+	add := func(candidate string) {
+		if !seen[candidate] {
+			seen[candidate] = true
+			out = append(out, candidate)
+		}
+	}
+
+	if strings.HasPrefix(text, "/") && !strings.Contains(text, " ") {
+		for _, v := range verbs {
+			if strings.HasPrefix(v, text[1:]) {
+				add("/" + v)
+			}
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	// nouns can be several words long ("floor egg") so try matching starting
+	// at each word boundary after the verb.
+	start := 0
+	if strings.HasPrefix(text, "/") {
+		start = strings.Index(text, " ") + 1
+	}
+
+	for ix := start; ix <= len(text); ix++ {
+		if ix != start && text[ix-1] != ' ' {
+			continue
+		}
+		fragment := strings.ToLower(text[ix:])
+		for _, n := range nouns {
+			if n != "" && strings.HasPrefix(strings.ToLower(n), fragment) {
+				add(text[:ix] + n)
+			}
+		}
+		if len(out) > 0 {
+			// prefer the longest fragment that matches anything
+			break
+		}
+	}
+
+	sort.Strings(out)
+	return out
+}
+
+// commonPrefix returns the longest prefix shared by all of candidates.
+func commonPrefix(candidates []string) string {
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	prefix := candidates[0]
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	return prefix
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	verbs := []string{"look", "go", "get", "give"}
+	nouns := []string{"floor egg", "Orb", "north"}
+
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "verb", text: "/lo", want: []string{"/look"}},
+		{name: "several verbs", text: "/g", want: []string{"/get", "/give", "/go"}},
+		{name: "no verb", text: "/zz", want: []string{}},
+		{name: "noun after verb", text: "/get fl", want: []string{"/get floor egg"}},
+		{name: "noun ignores case", text: "/get or", want: []string{"/get Orb"}},
+		{name: "noun in speech", text: "look at the no", want: []string{"look at the north"}},
+		{name: "multi-word noun", text: "/get floor e", want: []string{"/get floor egg"}},
+		{name: "no noun", text: "/get zz", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := complete(tt.text, verbs, nouns)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("complete(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommonPrefix(t *testing.T) {
+	tests := []struct {
+		candidates []string
+		want       string
+	}{
+		{candidates: nil, want: ""},
+		{candidates: []string{"/look"}, want: "/look"},
+		{candidates: []string{"/get", "/give", "/go"}, want: "/g"},
+		{candidates: []string{"abc", "xyz"}, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := commonPrefix(tt.candidates); got != tt.want {
+			t.Errorf("commonPrefix(%v) = %q, want %q", tt.candidates, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gdamore/tcell/v2"
+)
+
+const defaultAddress = "/tmp/hermeticum.sock"
+
+// actions that can be bound to keys in the [keys] table of the client config.
+const (
+	actionHistoryPrev = "history_prev"
+	actionHistoryNext = "history_next"
+	actionComplete    = "complete"
+	actionNewline     = "newline"
+	actionCancel      = "cancel"
+	actionPageUp      = "page_up"
+	actionPageDown    = "page_down"
+)
+
+func defaultKeys() map[string]string {
+	return map[string]string{
+		actionHistoryPrev: "Up",
+		actionHistoryNext: "Down",
+		actionComplete:    "Tab",
+		actionNewline:     "Ctrl-J",
+		actionCancel:      "Esc",
+		actionPageUp:      "PgUp",
+		actionPageDown:    "PgDn",
+	}
+}
+
+func configDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "hermeticum"), nil
+}
+
+// ConfigPath is where the client looks for its config when not told otherwise.
+func ConfigPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "client.toml"), nil
+}
+
+func DefaultConnectOpts() ConnectOpts {
+	opts := ConnectOpts{
+		Address:    defaultAddress,
+		Scrollback: 1000,
+		Keys:       defaultKeys(),
+		Aliases:    map[string]string{},
+	}
+
+	if dir, err := configDir(); err == nil {
+		opts.Theme = filepath.Join(dir, "theme.toml")
+	}
+
+	return opts
+}
+
+// LoadConnectOpts reads the client config at path on top of
+// DefaultConnectOpts. A missing file just means the defaults.
+func LoadConnectOpts(path string) (ConnectOpts, error) {
+	opts := DefaultConnectOpts()
+	opts.ConfigPath = path
+
+	_, err := toml.DecodeFile(path, &opts)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return opts, fmt.Errorf("could not read config %s: %w", path, err)
+	}
+
+	for _, t := range opts.Triggers {
+		if err := t.compile(); err != nil {
+			return opts, fmt.Errorf("bad trigger pattern in %s: %w", path, err)
+		}
+	}
+
+	for action, key := range defaultKeys() {
+		if _, ok := opts.Keys[action]; !ok {
+			opts.Keys[action] = key
+		}
+	}
+
+	opts.Theme = expandHome(opts.Theme)
+	opts.TranscriptDir = expandHome(opts.TranscriptDir)
+
+	return opts, nil
+}
+
+// expandHome replaces a leading ~/ in path with the user's home directory.
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, path[2:])
+}
+
+// parseKeys turns the [keys] table of the client config into a lookup from
+// key to action. Keys are named the way tcell names them, like "Up",
+// "PgDn" or "Ctrl-J".
+func parseKeys(bindings map[string]string) (map[tcell.Key]string, error) {
+	byName := map[string]tcell.Key{}
+	for k, name := range tcell.KeyNames {
+		byName[strings.ToLower(name)] = k
+	}
+
+	out := map[tcell.Key]string{}
+	for action, name := range bindings {
+		if _, ok := defaultKeys()[action]; !ok {
+			return nil, fmt.Errorf("unknown key binding action '%s'", action)
+		}
+		k, ok := byName[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown key '%s' bound to %s", name, action)
+		}
+		out[k] = action
+	}
+
+	return out, nil
+}
+
+// grpcTarget turns an address from the config into something grpc can dial.
+// Bare paths are taken to be unix sockets.
+func grpcTarget(address string) string {
+	if strings.HasPrefix(address, "/") {
+		return "unix://" + address
+	}
+
+	if strings.HasPrefix(address, ".") {
+		return "unix:" + address
+	}
+
+	return address
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const maxHistory = 1000
+
+// history is a readline style record of what the user has typed. It is
+// appended to a file as it grows so it survives between sessions.
+type history struct {
+	path    string
+	entries []string
+	// cursor is the entry currently shown while browsing with up/down. It is
+	// len(entries) when not browsing.
+	cursor int
+	// draft holds whatever was typed before browsing began so down can restore it.
+	draft string
+}
+
+func historyPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "history"), nil
+}
+
+// loadHistory reads path if it exists. A history that cannot be persisted is
+// still usable for the current session.
+func loadHistory(path string) *history {
+	h := &history{path: path}
+
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if line := scanner.Text(); line != "" {
+				h.entries = append(h.entries, line)
+			}
+		}
+		f.Close()
+	}
+
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+		h.rewrite()
+	}
+
+	h.cursor = len(h.entries)
+
+	return h
+}
+
+func (h *history) rewrite() {
+	if h.path == "" {
+		return
+	}
+
+	content := strings.Join(h.entries, "\n") + "\n"
+	if err := os.WriteFile(h.path, []byte(content), 0600); err != nil {
+		h.path = ""
+	}
+}
+
+// Add records line and stops browsing. Lines with newlines in them (from
+// multi-line input) are not recorded since the history file is line based.
+func (h *history) Add(line string) {
+	h.cursor = len(h.entries)
+	h.draft = ""
+
+	if line == "" || strings.Contains(line, "\n") {
+		return
+	}
+
+	if len(h.entries) > 0 && h.entries[len(h.entries)-1] == line {
+		return
+	}
+
+	h.entries = append(h.entries, line)
+	h.cursor = len(h.entries)
+
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[1:]
+		h.cursor = len(h.entries)
+		h.rewrite()
+		return
+	}
+
+	if h.path == "" {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		h.path = ""
+		return
+	}
+
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		h.path = ""
+		return
+	}
+	defer f.Close()
+
+	fmt.Fprintln(f, line)
+}
+
+// Prev returns the entry before the one being shown. current is what is
+// in the input right now and is remembered if browsing is just starting.
+func (h *history) Prev(current string) (string, bool) {
+	if h.cursor == 0 {
+		return "", false
+	}
+
+	if h.cursor == len(h.entries) {
+		h.draft = current
+	}
+
+	h.cursor--
+
+	return h.entries[h.cursor], true
+}
+
+// Next returns the entry after the one being shown, or the draft once
+// browsing runs off the end.
+func (h *history) Next() (string, bool) {
+	if h.cursor >= len(h.entries) {
+		return "", false
+	}
+
+	h.cursor++
+
+	if h.cursor == len(h.entries) {
+		return h.draft, true
+	}
+
+	return h.entries[h.cursor], true
+}
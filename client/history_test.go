@@ -0,0 +1,86 @@
+package client
+
+import (
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	type step struct {
+		// op is add, prev or next
+		op     string
+		arg    string
+		want   string
+		wantOK bool
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "nothing to browse",
+			steps: []step{
+				{op: "prev", arg: "draft", want: "", wantOK: false},
+				{op: "next", want: "", wantOK: false},
+			},
+		},
+		{
+			name: "browse back and return to the draft",
+			steps: []step{
+				{op: "add", arg: "one"},
+				{op: "add", arg: "two"},
+				{op: "prev", arg: "dra", want: "two", wantOK: true},
+				{op: "prev", want: "one", wantOK: true},
+				{op: "prev", want: "", wantOK: false},
+				{op: "next", want: "two", wantOK: true},
+				{op: "next", want: "dra", wantOK: true},
+				{op: "next", want: "", wantOK: false},
+			},
+		},
+		{
+			name: "repeats, blanks and multi-line input are not recorded",
+			steps: []step{
+				{op: "add", arg: "one"},
+				{op: "add", arg: "one"},
+				{op: "add", arg: ""},
+				{op: "add", arg: "a\nb"},
+				{op: "prev", want: "one", wantOK: true},
+				{op: "prev", want: "", wantOK: false},
+			},
+		},
+		{
+			name: "adding stops browsing",
+			steps: []step{
+				{op: "add", arg: "one"},
+				{op: "add", arg: "two"},
+				{op: "prev", want: "two", wantOK: true},
+				{op: "prev", want: "one", wantOK: true},
+				{op: "add", arg: "three"},
+				{op: "prev", want: "three", wantOK: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// an empty path keeps the history in memory
+			h := loadHistory("")
+			for ix, s := range tt.steps {
+				var got string
+				var ok bool
+				switch s.op {
+				case "add":
+					h.Add(s.arg)
+					continue
+				case "prev":
+					got, ok = h.Prev(s.arg)
+				case "next":
+					got, ok = h.Next()
+				}
+				if got != s.want || ok != s.wantOK {
+					t.Errorf("step %d %s: got %q, %v; want %q, %v", ix, s.op, got, ok, s.want, s.wantOK)
+				}
+			}
+		})
+	}
+}
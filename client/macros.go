@@ -0,0 +1,290 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/vilmibm/hermeticum/proto"
+)
+
+// triggerCooldown keeps a trigger that matches its own output from flooding
+// the server.
+const triggerCooldown = time.Second
+
+// Trigger watches the text of incoming events for Pattern. A match either
+// sends a command (with $1, ${name} etc. replaced by the pattern's captures)
+// or draws the whole line in the Highlight style.
+type Trigger struct {
+	Pattern   string `toml:"pattern"`
+	Send      string `toml:"send,omitempty"`
+	Highlight string `toml:"highlight,omitempty"`
+
+	re    *regexp.Regexp
+	fired time.Time
+}
+
+func (t *Trigger) compile() (err error) {
+	t.re, err = regexp.Compile(t.Pattern)
+	return
+}
+
+func (t *Trigger) String() string {
+	if t.Send != "" {
+		return fmt.Sprintf("/%s/ send %s", t.Pattern, t.Send)
+	}
+
+	return fmt.Sprintf("/%s/ highlight %s", t.Pattern, t.Highlight)
+}
+
+var aliasArg = regexp.MustCompile(`\$(\*|[1-9])`)
+
+// expandAlias checks whether input is a slash command naming an alias ("/gn"
+// for the alias gn) and, if so, returns the commands it expands to. An alias
+// is split into commands on ; before $1 through $9 are replaced by the words
+// following it and $* by all of them, so what the user typed can't add
+// commands of its own.
+func expandAlias(aliases map[string]string, input string) ([]string, bool) {
+	if !strings.HasPrefix(input, "/") {
+		return nil, false
+	}
+
+	name, args, _ := strings.Cut(input[1:], " ")
+	expansion, ok := aliases[name]
+	if !ok {
+		return nil, false
+	}
+
+	words := strings.Fields(args)
+
+	out := []string{}
+	for _, part := range strings.Split(expansion, ";") {
+		out = append(out, strings.TrimSpace(aliasArg.ReplaceAllStringFunc(part, func(ref string) string {
+			if ref == "$*" {
+				return strings.TrimSpace(args)
+			}
+			ix, _ := strconv.Atoi(ref[1:])
+			if ix > len(words) {
+				return ""
+			}
+			return words[ix-1]
+		})))
+	}
+
+	return out, true
+}
+
+// parseTrigger reads the arguments to /trigger:
+//
+//	/pattern/ send <command>
+//	/pattern/ highlight [style]
+func parseTrigger(rest string) (*Trigger, error) {
+	usage := errors.New("usage: /trigger /pattern/ send <command> or /trigger /pattern/ highlight [style]")
+
+	if !strings.HasPrefix(rest, "/") {
+		return nil, usage
+	}
+
+	end := strings.Index(rest[1:], "/ ") + 1
+	if end < 1 {
+		return nil, usage
+	}
+
+	t := &Trigger{Pattern: rest[1:end]}
+	action, arg, _ := strings.Cut(strings.TrimSpace(rest[end+1:]), " ")
+	arg = strings.TrimSpace(arg)
+
+	switch action {
+	case "send":
+		if arg == "" {
+			return nil, usage
+		}
+		t.Send = arg
+	case "highlight":
+		if arg == "" {
+			arg = DefaultTheme().KeywordStyle
+		}
+		t.Highlight = arg
+	default:
+		return nil, usage
+	}
+
+	if err := t.compile(); err != nil {
+		return nil, fmt.Errorf("bad pattern: %w", err)
+	}
+
+	return t, nil
+}
+
+func (cs *ClientState) handleAlias(rest string) {
+	name, expansion, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	// aliases are used as /name, and may be defined that way too
+	name = strings.TrimPrefix(name, "/")
+
+	if name == "" {
+		if len(cs.aliases) == 0 {
+			cs.printLocal("no aliases yet. try /alias gn /go north")
+			return
+		}
+		names := []string{}
+		for n := range cs.aliases {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		msg := "aliases:"
+		for _, n := range names {
+			msg += fmt.Sprintf("\n  /%s => %s", n, cs.aliases[n])
+		}
+		cs.printLocal(msg)
+		return
+	}
+
+	if expansion == "" {
+		if e, ok := cs.aliases[name]; ok {
+			cs.printLocal(fmt.Sprintf("/%s => %s", name, e))
+		} else {
+			cs.printLocal(fmt.Sprintf("no alias called %s", name))
+		}
+		return
+	}
+
+	cs.aliases[name] = strings.TrimSpace(expansion)
+	cs.saveMacros()
+	cs.printLocal(fmt.Sprintf("/%s => %s", name, cs.aliases[name]))
+}
+
+func (cs *ClientState) handleUnalias(rest string) {
+	name := strings.TrimPrefix(strings.TrimSpace(rest), "/")
+	if _, ok := cs.aliases[name]; !ok {
+		cs.printLocal(fmt.Sprintf("no alias called %s", name))
+		return
+	}
+
+	delete(cs.aliases, name)
+	cs.saveMacros()
+	cs.printLocal(fmt.Sprintf("forgot alias %s", name))
+}
+
+func (cs *ClientState) handleTrigger(rest string) {
+	if strings.TrimSpace(rest) == "" {
+		if len(cs.triggers) == 0 {
+			cs.printLocal("no triggers yet. try /trigger /hungry/ send /emote offers a snack")
+			return
+		}
+		msg := "triggers:"
+		for ix, t := range cs.triggers {
+			msg += fmt.Sprintf("\n  %d. %s", ix+1, t)
+		}
+		cs.printLocal(msg)
+		return
+	}
+
+	t, err := parseTrigger(rest)
+	if err != nil {
+		cs.printLocal(err.Error())
+		return
+	}
+
+	cs.triggers = append(cs.triggers, t)
+	cs.saveMacros()
+	cs.printLocal(fmt.Sprintf("%d. %s", len(cs.triggers), t))
+}
+
+func (cs *ClientState) handleUntrigger(rest string) {
+	ix, err := strconv.Atoi(strings.TrimSpace(rest))
+	if err != nil || ix < 1 || ix > len(cs.triggers) {
+		cs.printLocal("usage: /untrigger <number>. use /trigger to see the numbers.")
+		return
+	}
+
+	t := cs.triggers[ix-1]
+	cs.triggers = append(cs.triggers[:ix-1], cs.triggers[ix:]...)
+	cs.saveMacros()
+	cs.printLocal(fmt.Sprintf("forgot trigger %s", t))
+}
+
+// fireTriggers sends the commands of any send triggers matching ev.
+func (cs *ClientState) fireTriggers(ev *proto.WorldEvent) {
+	text := eventText(ev)
+	cmds := []*proto.Command{}
+	for _, t := range cs.triggers {
+		if t.Send == "" || time.Since(t.fired) < triggerCooldown {
+			continue
+		}
+		match := t.re.FindStringSubmatchIndex(text)
+		if match == nil {
+			continue
+		}
+		t.fired = time.Now()
+		// split before expanding so a ; in the matched text can't add commands
+		for _, part := range strings.Split(t.Send, ";") {
+			input := string(t.re.ExpandString(nil, part, text, match))
+			cmds = append(cmds, ParseInput(strings.TrimSpace(input)))
+		}
+	}
+
+	if len(cmds) == 0 {
+		return
+	}
+
+	// this runs on the tview event loop, which must not wait on the main loop
+	go func() {
+		for _, cmd := range cmds {
+			cs.cio.outbound <- cmd
+		}
+	}()
+}
+
+// highlightFor returns the style of the first highlight trigger matching ev.
+func (cs *ClientState) highlightFor(ev *proto.WorldEvent) string {
+	text := eventText(ev)
+	for _, t := range cs.triggers {
+		if t.Highlight != "" && t.re.MatchString(text) {
+			return t.Highlight
+		}
+	}
+
+	return ""
+}
+
+// saveMacros writes aliases and triggers back into the client config. The
+// rest of the file is left as it was, though comments don't survive.
+func (cs *ClientState) saveMacros() {
+	if cs.configPath == "" {
+		return
+	}
+
+	config := map[string]interface{}{}
+	_, err := toml.DecodeFile(cs.configPath, &config)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		cs.printLocal(fmt.Sprintf("not saving: could not read %s: %s", cs.configPath, err.Error()))
+		return
+	}
+
+	config["aliases"] = cs.aliases
+	config["triggers"] = cs.triggers
+
+	if err = os.MkdirAll(filepath.Dir(cs.configPath), 0700); err != nil {
+		cs.printLocal(fmt.Sprintf("not saving: %s", err.Error()))
+		return
+	}
+
+	f, err := os.Create(cs.configPath)
+	if err != nil {
+		cs.printLocal(fmt.Sprintf("not saving: %s", err.Error()))
+		return
+	}
+	defer f.Close()
+
+	if err = toml.NewEncoder(f).Encode(config); err != nil {
+		cs.printLocal(fmt.Sprintf("failed to save %s: %s", cs.configPath, err.Error()))
+	}
+}
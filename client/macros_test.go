@@ -0,0 +1,49 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandAlias(t *testing.T) {
+	aliases := map[string]string{
+		"gn":    "/go north",
+		"hug":   "/emote hugs $1 warmly",
+		"tour":  "/go north; say hi $*; /go south",
+		"hello": "say hello",
+	}
+
+	tests := []struct {
+		name   string
+		input  string
+		want   []string
+		wantOK bool
+	}{
+		{name: "plain", input: "/gn", want: []string{"/go north"}, wantOK: true},
+		{name: "numbered argument", input: "/hug vilmibm", want: []string{"/emote hugs vilmibm warmly"}, wantOK: true},
+		{name: "missing argument", input: "/hug", want: []string{"/emote hugs  warmly"}, wantOK: true},
+		{
+			name:   "several commands",
+			input:  "/tour everyone",
+			want:   []string{"/go north", "say hi everyone", "/go south"},
+			wantOK: true,
+		},
+		{
+			name:   "semicolons in arguments stay put",
+			input:  "/tour a; /quit",
+			want:   []string{"/go north", "say hi a; /quit", "/go south"},
+			wantOK: true,
+		},
+		{name: "speech starting with an alias name", input: "hello there", want: nil, wantOK: false},
+		{name: "not an alias", input: "/look", want: nil, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := expandAlias(aliases, tt.input)
+			if !reflect.DeepEqual(got, tt.want) || ok != tt.wantOK {
+				t.Errorf("expandAlias(%q) = %q, %v; want %q, %v", tt.input, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/vilmibm/hermeticum/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+type PipeOpts struct {
+	Address string
+	// JSON writes each WorldEvent as a line of JSON instead of as plain text.
+	JSON bool
+	// Linger is how long to keep listening for events after input runs out
+	// before quitting.
+	Linger time.Duration
+}
+
+// Pipe is a line mode client for bots and scripts. Each line read from in is
+// sent as a command (just like typing it into the TUI) and every event the
+// server sends is written to out.
+func Pipe(opts PipeOpts, in io.Reader, out io.Writer) error {
+	sess, err := Dial(opts.Address)
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+
+	quit := func() {
+		if err := sess.Send(&proto.Command{Verb: "quit"}); err != nil {
+			log.Printf("failed to send quit: %s", err.Error())
+		}
+	}
+
+	sigC := make(chan os.Signal, 1)
+	signal.Notify(sigC, os.Interrupt)
+	defer signal.Stop(sigC)
+
+	go func() {
+		<-sigC
+		quit()
+	}()
+
+	go func() {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			if err := sess.Send(ParseInput(scanner.Text())); err != nil {
+				log.Printf("failed to send command: %s", err.Error())
+				return
+			}
+		}
+		time.Sleep(opts.Linger)
+		quit()
+	}()
+
+	for ev := range sess.Events() {
+		if err := writeEvent(out, ev, opts.JSON); err != nil {
+			return err
+		}
+	}
+
+	if err := sess.Err(); err != nil && err != io.EOF {
+		return err
+	}
+
+	return nil
+}
+
+func writeEvent(out io.Writer, ev *proto.WorldEvent, asJSON bool) error {
+	if asJSON {
+		line, err := protojson.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%s\n", line)
+		return err
+	}
+
+	// there's nothing to read in a room snapshot
+	if ev.Type == proto.WorldEvent_ROOM {
+		return nil
+	}
+
+	_, err := fmt.Fprintln(out, eventText(ev))
+	return err
+}
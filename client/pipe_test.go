@@ -0,0 +1,76 @@
+package client
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/vilmibm/hermeticum/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	gproto "google.golang.org/protobuf/proto"
+)
+
+func TestWriteEvent(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name string
+		ev   *proto.WorldEvent
+		want string
+	}{
+		{
+			name: "speech",
+			ev:   &proto.WorldEvent{Type: proto.WorldEvent_OVERHEARD, Source: str("vilmibm"), Text: str("hi")},
+			want: "vilmibm: hi\n",
+		},
+		{
+			name: "emote",
+			ev:   &proto.WorldEvent{Type: proto.WorldEvent_EMOTE, Source: str("vilmibm"), Text: str("waves")},
+			want: "vilmibm waves\n",
+		},
+		{
+			name: "print",
+			ev:   &proto.WorldEvent{Type: proto.WorldEvent_PRINT, Text: str("you dig north.")},
+			want: "you dig north.\n",
+		},
+		{
+			name: "arrival without text",
+			ev:   &proto.WorldEvent{Type: proto.WorldEvent_ENTER, Source: str("vilmibm")},
+			want: "vilmibm arrives.\n",
+		},
+		{
+			name: "room snapshots are skipped",
+			ev:   &proto.WorldEvent{Type: proto.WorldEvent_ROOM},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := writeEvent(&out, tt.ev, false); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+
+			// every event, room snapshots included, is one line of JSON
+			out.Reset()
+			if err := writeEvent(&out, tt.ev, true); err != nil {
+				t.Fatal(err)
+			}
+			line := out.String()
+			if strings.Count(line, "\n") != 1 || !strings.HasSuffix(line, "\n") {
+				t.Fatalf("JSON output %q isn't one line", line)
+			}
+			got := &proto.WorldEvent{}
+			if err := protojson.Unmarshal([]byte(line), got); err != nil {
+				t.Fatal(err)
+			}
+			if !gproto.Equal(got, tt.ev) {
+				t.Errorf("JSON round trip got %v, want %v", got, tt.ev)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package client
+
+import "github.com/vilmibm/hermeticum/proto"
+
+// scrollback is a fixed size ring buffer of the events a user has seen. The
+// messages view is rebuilt from it now and then so the view never holds much
+// more than it does.
+type scrollback struct {
+	events []*proto.WorldEvent
+	next   int
+	full   bool
+	// evicted counts the events pushed out so far
+	evicted int
+}
+
+func newScrollback(size int) *scrollback {
+	if size < 1 {
+		size = 1
+	}
+
+	return &scrollback{
+		events: make([]*proto.WorldEvent, size),
+	}
+}
+
+// Add records ev, returning true if doing so pushed the oldest event out.
+func (sb *scrollback) Add(ev *proto.WorldEvent) (evicted bool) {
+	evicted = sb.full
+	if evicted {
+		sb.evicted++
+	}
+	sb.events[sb.next] = ev
+	sb.next = (sb.next + 1) % len(sb.events)
+	if sb.next == 0 {
+		sb.full = true
+	}
+
+	return
+}
+
+// Size is how many events the scrollback can hold.
+func (sb *scrollback) Size() int {
+	return len(sb.events)
+}
+
+// Offset is the number of events that have been pushed out, which makes it
+// the position of the first of Events among all the events ever added.
+func (sb *scrollback) Offset() int {
+	return sb.evicted
+}
+
+// Events returns the buffered events from oldest to newest.
+func (sb *scrollback) Events() []*proto.WorldEvent {
+	if !sb.full {
+		return append([]*proto.WorldEvent{}, sb.events[:sb.next]...)
+	}
+
+	out := append([]*proto.WorldEvent{}, sb.events[sb.next:]...)
+	return append(out, sb.events[:sb.next]...)
+}
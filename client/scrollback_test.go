@@ -0,0 +1,66 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vilmibm/hermeticum/proto"
+)
+
+func TestScrollback(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int
+		add        []string
+		want       []string
+		wantOffset int
+		// wantEvicted is what the last Add returned
+		wantEvicted bool
+	}{
+		{name: "empty", size: 3, add: nil, want: []string{}},
+		{name: "partial", size: 3, add: []string{"a", "b"}, want: []string{"a", "b"}},
+		{name: "exactly full", size: 3, add: []string{"a", "b", "c"}, want: []string{"a", "b", "c"}},
+		{
+			name:        "wrapped",
+			size:        3,
+			add:         []string{"a", "b", "c", "d", "e"},
+			want:        []string{"c", "d", "e"},
+			wantOffset:  2,
+			wantEvicted: true,
+		},
+		{
+			name:        "size below one holds one",
+			size:        0,
+			add:         []string{"a", "b"},
+			want:        []string{"b"},
+			wantOffset:  1,
+			wantEvicted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sb := newScrollback(tt.size)
+			evicted := false
+			for _, text := range tt.add {
+				text := text
+				evicted = sb.Add(&proto.WorldEvent{Text: &text})
+			}
+
+			got := []string{}
+			for _, ev := range sb.Events() {
+				got = append(got, ev.GetText())
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Events() = %v, want %v", got, tt.want)
+			}
+			if sb.Offset() != tt.wantOffset {
+				t.Errorf("Offset() = %d, want %d", sb.Offset(), tt.wantOffset)
+			}
+			if evicted != tt.wantEvicted {
+				t.Errorf("last Add() = %v, want %v", evicted, tt.wantEvicted)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vilmibm/hermeticum/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Session is a connection to a hermeticum server. Commands go in with Send
+// and WorldEvents come out of Events. Both the TUI and the line mode client
+// are built on it and it can be used to write bots.
+type Session struct {
+	conn       *grpc.ClientConn
+	client     proto.GameWorldClient
+	stream     proto.GameWorld_ClientInputClient
+	events     chan *proto.WorldEvent
+	err        error
+	sendMutex  sync.Mutex
+	closeMutex sync.Mutex
+}
+
+// Dial connects to the server at address (see ConnectOpts.Address), checks
+// that it is alive and opens a command stream. The server rezzes the
+// user's avatar as soon as the stream opens.
+func Dial(address string) (*Session, error) {
+	conn, err := grpc.NewClient(
+		grpcTarget(address),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Session{
+		conn:   conn,
+		client: proto.NewGameWorldClient(conn),
+		events: make(chan *proto.WorldEvent),
+	}
+
+	now := fmt.Sprintf("%d", time.Now().Unix())
+	if _, err = s.client.Ping(context.Background(), &proto.PingMsg{When: now}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("could not ping server at %s: %w", address, err)
+	}
+
+	if s.stream, err = s.client.ClientInput(context.Background()); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("could not create command stream: %w", err)
+	}
+
+	go func() {
+		defer close(s.events)
+		for {
+			ev, err := s.stream.Recv()
+			if err != nil {
+				s.closeMutex.Lock()
+				s.err = err
+				s.closeMutex.Unlock()
+				return
+			}
+			s.events <- ev
+		}
+	}()
+
+	return s, nil
+}
+
+// Events delivers everything the server sends. It is closed when the stream
+// ends; Err then says why.
+func (s *Session) Events() <-chan *proto.WorldEvent {
+	return s.events
+}
+
+func (s *Session) Err() error {
+	s.closeMutex.Lock()
+	defer s.closeMutex.Unlock()
+	return s.err
+}
+
+// Send is safe to call from multiple goroutines.
+func (s *Session) Send(cmd *proto.Command) error {
+	s.sendMutex.Lock()
+	defer s.sendMutex.Unlock()
+	return s.stream.Send(cmd)
+}
+
+// Close ends the session without telling the server to quit first.
+func (s *Session) Close() error {
+	return s.conn.Close()
+}
+
+// ParseInput turns something a user typed into a Command. Input starting with
+// a slash is a verb and its arguments; anything else is said out loud.
+func ParseInput(input string) *proto.Command {
+	var verb string
+	rest := input
+	if strings.HasPrefix(input, "/") {
+		verb, rest, _ = strings.Cut(input[1:], " ")
+	} else {
+		verb = "say"
+	}
+
+	return &proto.Command{
+		Verb: verb,
+		Rest: rest,
+	}
+}
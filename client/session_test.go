@@ -0,0 +1,32 @@
+package client
+
+import (
+	"testing"
+)
+
+func TestParseInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantVerb string
+		wantRest string
+	}{
+		{name: "speech", input: "hello there", wantVerb: "say", wantRest: "hello there"},
+		{name: "verb", input: "/look", wantVerb: "look", wantRest: ""},
+		{name: "verb with rest", input: "/get floor egg", wantVerb: "get", wantRest: "floor egg"},
+		{name: "only the first space splits", input: "/emote  waves", wantVerb: "emote", wantRest: " waves"},
+		{name: "slash later on", input: "and/or", wantVerb: "say", wantRest: "and/or"},
+		{name: "bare slash", input: "/", wantVerb: "", wantRest: ""},
+		{name: "nothing", input: "", wantVerb: "say", wantRest: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := ParseInput(tt.input)
+			if cmd.Verb != tt.wantVerb || cmd.Rest != tt.wantRest {
+				t.Errorf("ParseInput(%q) = %q %q, want %q %q",
+					tt.input, cmd.Verb, cmd.Rest, tt.wantVerb, tt.wantRest)
+			}
+		})
+	}
+}
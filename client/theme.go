@@ -0,0 +1,252 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/rivo/tview"
+	"github.com/vilmibm/hermeticum/proto"
+)
+
+// Theme controls how each kind of WorldEvent is drawn. Styles are tview color
+// tags without the brackets, like "yellow", "#ff8800" or "red::b". An empty
+// style leaves text in the terminal's default color.
+type Theme struct {
+	Whisper   string `toml:"whisper"`
+	Overheard string `toml:"overheard"`
+	Emote     string `toml:"emote"`
+	Print     string `toml:"print"`
+	Global    string `toml:"global"`
+	Shout     string `toml:"shout"`
+	Enter     string `toml:"enter"`
+	Leave     string `toml:"leave"`
+	Presence  string `toml:"presence"`
+
+	// Source is used for the name of whoever caused an event and Self for
+	// your own name wherever it appears.
+	Source string `toml:"source"`
+	Self   string `toml:"self"`
+
+	// Keywords are highlighted with KeywordStyle wherever they appear.
+	Keywords     []string `toml:"keywords"`
+	KeywordStyle string   `toml:"keyword_style"`
+
+	// Bell rings the terminal bell when someone whispers to you.
+	Bell bool `toml:"bell"`
+}
+
+func DefaultTheme() *Theme {
+	return &Theme{
+		Whisper:      "fuchsia",
+		Overheard:    "",
+		Emote:        "lightskyblue",
+		Print:        "",
+		Global:       "yellow::b",
+		Shout:        "orange::b",
+		Enter:        "gray",
+		Leave:        "gray",
+		Presence:     "gray",
+		Source:       "::b",
+		Self:         "lime::b",
+		KeywordStyle: "black:yellow",
+		Bell:         true,
+	}
+}
+
+// LoadTheme reads the theme at path on top of DefaultTheme. A missing file
+// (or no path at all) just means the defaults.
+func LoadTheme(path string) (*Theme, error) {
+	t := DefaultTheme()
+	if path == "" {
+		return t, nil
+	}
+
+	_, err := toml.DecodeFile(path, t)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("could not read theme %s: %w", path, err)
+	}
+
+	return t, nil
+}
+
+// eventText is the plain text a user should see for ev.
+func eventText(ev *proto.WorldEvent) string {
+	return renderEvent(ev, nil, "")
+}
+
+// renderEvent turns ev into a line for the messages view, colored according
+// to t. self is the user's own name. A nil theme produces plain text with no
+// tags at all.
+func renderEvent(ev *proto.WorldEvent, t *Theme, self string) string {
+	p := painter{}
+	if t != nil {
+		p = painter{theme: t, self: self, base: t.styleFor(ev.Type)}
+	}
+
+	source := p.name(ev.GetSource())
+	text := p.text(ev.GetText())
+
+	var line string
+	switch ev.Type {
+	case proto.WorldEvent_WHISPER:
+		line = fmt.Sprintf("%s whispers: %s", source, text)
+	case proto.WorldEvent_OVERHEARD:
+		line = fmt.Sprintf("%s: %s", source, text)
+	case proto.WorldEvent_EMOTE:
+		line = fmt.Sprintf("%s %s", source, text)
+	case proto.WorldEvent_PRINT:
+		line = text
+	case proto.WorldEvent_GLOBAL:
+		line = fmt.Sprintf("*** %s", text)
+		if ev.Source != nil {
+			line = fmt.Sprintf("*** %s announces: %s", source, text)
+		}
+	case proto.WorldEvent_SHOUT:
+		line = fmt.Sprintf("%s shouts: %s", source, text)
+	case proto.WorldEvent_ENTER:
+		if ev.Text == nil {
+			text = "arrives."
+		}
+		line = fmt.Sprintf("%s %s", source, text)
+	case proto.WorldEvent_LEAVE:
+		if ev.Text == nil {
+			text = "leaves."
+		}
+		line = fmt.Sprintf("%s %s", source, text)
+	case proto.WorldEvent_PRESENCE:
+		line = fmt.Sprintf("%s disconnects.", source)
+		if ev.GetPresence().GetOnline() {
+			line = fmt.Sprintf("%s connects.", source)
+		}
+	default:
+		line = strings.TrimSpace(fmt.Sprintf("%s %s", source, text))
+	}
+
+	return p.line(line)
+}
+
+func (t *Theme) styleFor(typ proto.WorldEvent_WorldEventType) string {
+	switch typ {
+	case proto.WorldEvent_WHISPER:
+		return t.Whisper
+	case proto.WorldEvent_OVERHEARD:
+		return t.Overheard
+	case proto.WorldEvent_EMOTE:
+		return t.Emote
+	case proto.WorldEvent_PRINT:
+		return t.Print
+	case proto.WorldEvent_GLOBAL:
+		return t.Global
+	case proto.WorldEvent_SHOUT:
+		return t.Shout
+	case proto.WorldEvent_ENTER:
+		return t.Enter
+	case proto.WorldEvent_LEAVE:
+		return t.Leave
+	case proto.WorldEvent_PRESENCE:
+		return t.Presence
+	}
+
+	return ""
+}
+
+// painter wraps pieces of a line in color tags. tview tags don't nest, so
+// each piece switches back to the line's base style when it ends.
+type painter struct {
+	theme *Theme
+	self  string
+	base  string
+}
+
+func (p painter) paint(style, s string) string {
+	if p.theme == nil || style == "" || s == "" {
+		return s
+	}
+
+	return fmt.Sprintf("[%s]%s[%s]", style, s, fullStyle(p.base))
+}
+
+// fullStyle spells out every field of a color tag so switching to it also
+// clears whatever attributes the previous tag set. "yellow::b" becomes
+// "yellow:-:b" and "" becomes "-:-:-".
+func fullStyle(style string) string {
+	fields := strings.SplitN(style, ":", 3)
+	for len(fields) < 3 {
+		fields = append(fields, "")
+	}
+	for ix, f := range fields {
+		if f == "" {
+			fields[ix] = "-"
+		}
+	}
+
+	return strings.Join(fields, ":")
+}
+
+func (p painter) line(s string) string {
+	if p.theme == nil || p.base == "" {
+		return s
+	}
+
+	return fmt.Sprintf("[%s]%s[-:-:-]", p.base, s)
+}
+
+func (p painter) name(s string) string {
+	if p.theme == nil {
+		return s
+	}
+
+	if p.self != "" && s == p.self {
+		return p.paint(p.theme.Self, tview.Escape(s))
+	}
+
+	return p.paint(p.theme.Source, tview.Escape(s))
+}
+
+func (p painter) text(s string) string {
+	if p.theme == nil {
+		return s
+	}
+
+	words := map[string]string{}
+	for _, k := range p.theme.Keywords {
+		words[strings.ToLower(k)] = p.theme.KeywordStyle
+	}
+	if p.self != "" {
+		words[strings.ToLower(p.self)] = p.theme.Self
+	}
+
+	return p.highlight(s, words)
+}
+
+// highlight escapes s and paints each case insensitive occurrence of a key in
+// words with its style.
+func (p painter) highlight(s string, words map[string]string) string {
+	var b strings.Builder
+	last := 0
+	for ix := 0; ix < len(s); {
+		match := ""
+		for w := range words {
+			end := ix + len(w)
+			if w != "" && end <= len(s) && strings.EqualFold(s[ix:end], w) && len(w) > len(match) {
+				match = w
+			}
+		}
+
+		if match == "" {
+			ix++
+			continue
+		}
+
+		b.WriteString(tview.Escape(s[last:ix]))
+		b.WriteString(p.paint(words[match], tview.Escape(s[ix:ix+len(match)])))
+		ix += len(match)
+		last = ix
+	}
+	b.WriteString(tview.Escape(s[last:]))
+
+	return b.String()
+}
@@ -1,18 +1,69 @@
 package cmd
 
 import (
+	"os"
+	"time"
+
 	"github.com/spf13/cobra"
 	"github.com/vilmibm/hermeticum/client"
 )
 
 func init() {
+	f := connectCmd.Flags()
+	f.String("config", "", "path to the client config (default ~/.config/hermeticum/client.toml)")
+	f.String("address", "", "server unix socket path or address")
+	f.String("theme", "", "path to a theme file")
+	f.Int("scrollback", 0, "number of messages to keep for scrolling and /find")
+	f.String("transcript", "", "write a timestamped transcript of the session into this directory")
+	f.Bool("plain", false, "skip the TUI: read commands from stdin and write events to stdout")
+	f.Bool("json", false, "like --plain but write events as newline delimited JSON")
+	f.Duration("linger", time.Second, "with --plain, how long to wait for events after stdin closes")
 	rootCmd.AddCommand(connectCmd)
 }
 
 var connectCmd = &cobra.Command{
 	Use: "connect",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		opts := client.ConnectOpts{}
+		f := cmd.Flags()
+
+		path, _ := f.GetString("config")
+		if path == "" {
+			var err error
+			if path, err = client.ConfigPath(); err != nil {
+				return err
+			}
+		}
+
+		opts, err := client.LoadConnectOpts(path)
+		if err != nil {
+			return err
+		}
+
+		if f.Changed("address") {
+			opts.Address, _ = f.GetString("address")
+		}
+		if f.Changed("theme") {
+			opts.Theme, _ = f.GetString("theme")
+		}
+		if f.Changed("scrollback") {
+			opts.Scrollback, _ = f.GetInt("scrollback")
+		}
+		if f.Changed("transcript") {
+			opts.TranscriptDir, _ = f.GetString("transcript")
+		}
+
+		plain, _ := f.GetBool("plain")
+		asJSON, _ := f.GetBool("json")
+		if plain || asJSON {
+			linger, _ := f.GetDuration("linger")
+			popts := client.PipeOpts{
+				Address: opts.Address,
+				JSON:    asJSON,
+				Linger:  linger,
+			}
+			return client.Pipe(popts, os.Stdin, os.Stdout)
+		}
+
 		return client.Connect(opts)
 	},
 }
@@ -6,13 +6,21 @@ import (
 )
 
 func init() {
+	f := resetCmd.Flags()
+	f.String("config", "", "path to the server config (default ~/.config/hermeticum/server.toml)")
+	f.String("dsn", "", "postgres connection string (default uses PG* environment variables)")
 	rootCmd.AddCommand(resetCmd)
 }
 
 var resetCmd = &cobra.Command{
 	Use: "reset",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		hdb, err := db.NewDB()
+		sopts, err := serveOpts(cmd)
+		if err != nil {
+			return err
+		}
+
+		hdb, err := db.NewDB(sopts.DSN)
 		if err != nil {
 			return err
 		}
@@ -6,13 +6,48 @@ import (
 )
 
 func init() {
+	f := serveCmd.Flags()
+	f.String("config", "", "path to the server config (default ~/.config/hermeticum/server.toml)")
+	f.String("address", "", "unix socket path to listen on")
+	f.String("dsn", "", "postgres connection string (default uses PG* environment variables)")
 	rootCmd.AddCommand(serveCmd)
 }
 
+// serveOpts loads the server config named by cmd's --config flag and applies
+// any flags given on top of it.
+func serveOpts(cmd *cobra.Command) (server.ServeOpts, error) {
+	f := cmd.Flags()
+
+	path, _ := f.GetString("config")
+	if path == "" {
+		var err error
+		if path, err = server.ConfigPath(); err != nil {
+			return server.ServeOpts{}, err
+		}
+	}
+
+	opts, err := server.LoadServeOpts(path)
+	if err != nil {
+		return opts, err
+	}
+
+	if f.Changed("address") {
+		opts.Address, _ = f.GetString("address")
+	}
+	if f.Changed("dsn") {
+		opts.DSN, _ = f.GetString("dsn")
+	}
+
+	return opts, nil
+}
+
 var serveCmd = &cobra.Command{
 	Use: "serve",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		opts := server.ServeOpts{}
+		opts, err := serveOpts(cmd)
+		if err != nil {
+			return err
+		}
 		return server.Serve(opts)
 	},
 }
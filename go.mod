@@ -31,4 +31,7 @@ require (
 	google.golang.org/genproto v0.0.0-20200526211855-cb27e3aa2013 // indirect
 )
 
-require github.com/yuin/gopher-lua v0.0.0-20221210110428-332342483e3f
+require (
+	github.com/BurntSushi/toml v1.4.0
+	github.com/yuin/gopher-lua v0.0.0-20221210110428-332342483e3f
+)
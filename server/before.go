@@ -0,0 +1,108 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/vilmibm/hermeticum/server/db"
+	"github.com/vilmibm/hermeticum/server/witch"
+)
+
+// allowed gives the scripts of everything near avatar, and of subject if it
+// is somewhere else, a chance to deny or rewrite a builtin action (see
+// WITCH's before). It returns the possibly rewritten rest or, having told
+// avatar why, false.
+//
+// Rewrites aren't checked again, so an object a rest is rewritten to refer to
+// doesn't get a say.
+func (s *gameWorldServer) allowed(avatar db.Object, action, rest string, subject *db.Object) (string, bool, error) {
+	v, err := s.consult(witch.Action{Name: action, Rest: rest, Sender: avatar, Object: subject}, 0)
+	if err != nil {
+		return rest, false, err
+	}
+
+	if v.Denied {
+		reason := v.Reason
+		if reason == "" {
+			reason = "something stops you."
+		}
+		s.printTo(avatar, reason)
+		return rest, false, nil
+	}
+
+	return v.Rest, true, nil
+}
+
+// allowedMove is allowed for a script (mover's) moving obj into dest (see
+// WITCH's move). It counts as obj going somewhere, so before("go") handlers
+// near obj and dest's own get a say. mover isn't asked since it's the one
+// asking. Why a move was denied goes back to the script rather than to obj.
+// hops is how many sends led to the move (see witch.Action).
+func (s *gameWorldServer) allowedMove(mover, obj, dest db.Object, hops int) (bool, string) {
+	a := witch.Action{Name: "go", Rest: s.db.RefFor(dest), Sender: obj, Object: &dest, Hops: hops}
+	v, err := s.consult(a, mover.ID)
+	if err != nil {
+		log.Printf("failed to check whether %d can move %d into %d: %s", mover.ID, obj.ID, dest.ID, err.Error())
+		return false, "the move failed"
+	}
+
+	if v.Denied {
+		reason := v.Reason
+		if reason == "" {
+			reason = fmt.Sprintf("something stops %s.", obj.GetData("name"))
+		}
+		return false, reason
+	}
+
+	return true, ""
+}
+
+// consult does the asking for allowed and allowedMove: everything near
+// a.Sender, and a.Object, in turn. The object with the ID skip, if any, isn't
+// asked.
+func (s *gameWorldServer) consult(a witch.Action, skip int) (witch.Verdict, error) {
+	v := witch.Verdict{Rest: a.Rest}
+
+	nearby, err := a.Sender.Earshot(s.db)
+	if err != nil {
+		return v, err
+	}
+
+	if a.Object != nil && !slices.ContainsFunc(nearby, func(o *db.Object) bool { return o.ID == a.Object.ID }) {
+		nearby = append(nearby, a.Object)
+	}
+
+	for _, o := range nearby {
+		if o.ID == skip {
+			continue
+		}
+		if o.Perms.Exec == db.PermOwner && a.Sender.OwnerID != o.OwnerID {
+			continue
+		}
+
+		sc, err := s.scriptFor(*o)
+		if err != nil {
+			return v, err
+		}
+
+		a.Rest = v.Rest
+		v = sc.Before(*o, a)
+		if v.Denied {
+			return v, nil
+		}
+	}
+
+	return v, nil
+}
+
+// likelySubject is what term means among candidates if it clearly means one
+// thing. It doesn't ask the player to choose like resolveOne does.
+func (s *gameWorldServer) likelySubject(avatar db.Object, candidates []*db.Object, term string) *db.Object {
+	os, err := s.db.Match(avatar, candidates, term)
+	if err != nil || len(os) != 1 {
+		return nil
+	}
+
+	return os[0]
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vilmibm/hermeticum/proto"
+	"github.com/vilmibm/hermeticum/server/db"
+)
+
+func init() {
+	registerVerb(&verb{
+		Name:    "code",
+		Usage:   "<thing> [as <code>]",
+		Help:    "show the short code scripts and commands can use to refer to thing, or give something you own a new one. codes are lowercase letters, numbers, - and _.",
+		Handler: (*gameWorldServer).handleCode,
+	})
+}
+
+func (s *gameWorldServer) handleCode(avatar db.Object, cmd *proto.Command) error {
+	term, code, setting := strings.Cut(cmd.Rest, " as ")
+	term = strings.TrimSpace(term)
+	code = strings.TrimSpace(code)
+	if term == "" {
+		s.printTo(avatar, "usage: /code <thing> [as <code>]")
+		return nil
+	}
+
+	candidates, err := avatar.Earshot(s.db)
+	if err != nil {
+		return err
+	}
+	held, err := avatar.Contents(s.db)
+	if err != nil {
+		return err
+	}
+	candidates = append(candidates, held...)
+
+	target, err := s.resolveOne(avatar, cmd, candidates, term,
+		fmt.Sprintf("you don't see anything called '%s' here.", term))
+	if err != nil || target == nil {
+		return err
+	}
+
+	if !setting {
+		if target.Code == "" {
+			s.printTo(avatar, fmt.Sprintf("%s has no code. it can be referred to as %s.",
+				target.String(), s.db.RefFor(*target)))
+			return nil
+		}
+		s.printTo(avatar, fmt.Sprintf("%s is %s.", target.String(), s.db.RefFor(*target)))
+		return nil
+	}
+
+	if target.OwnerID != avatar.OwnerID {
+		s.printTo(avatar, fmt.Sprintf("%s isn't yours to name.", target.String()))
+		return nil
+	}
+
+	err = target.SetCode(s.db, code)
+	if errors.Is(err, db.ErrInvalidCode) {
+		s.printTo(avatar, fmt.Sprintf("'%s' won't do. codes are lowercase letters, numbers, - and _.", code))
+		return nil
+	}
+	if errors.Is(err, db.ErrCodeTaken) {
+		s.printTo(avatar, fmt.Sprintf("you already have something with the code '%s'.", code))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s.printTo(avatar, fmt.Sprintf("%s is now %s.", target.String(), s.db.RefFor(*target)))
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+const defaultAddress = "/tmp/hermeticum.sock"
+
+// ConfigPath is where the server looks for its config when not told otherwise.
+func ConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "hermeticum", "server.toml"), nil
+}
+
+func DefaultServeOpts() ServeOpts {
+	return ServeOpts{
+		Address:     defaultAddress,
+		ObjectQuota: 1000,
+	}
+}
+
+// LoadServeOpts reads the server config at path on top of DefaultServeOpts.
+// A missing file just means the defaults.
+func LoadServeOpts(path string) (ServeOpts, error) {
+	opts := DefaultServeOpts()
+
+	_, err := toml.DecodeFile(path, &opts)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return opts, fmt.Errorf("could not read config %s: %w", path, err)
+	}
+
+	return opts, nil
+}
+
+// socketPath pulls a filesystem path out of address. Users are identified by
+// their peer credentials, so only unix sockets can be listened on.
+func socketPath(address string) (string, error) {
+	path := strings.TrimPrefix(strings.TrimPrefix(address, "unix://"), "unix:")
+	if !strings.HasPrefix(path, "/") && !strings.HasPrefix(path, ".") {
+		return "", fmt.Errorf("cannot listen on '%s': hermeticum authenticates with peer credentials and needs a unix socket path", address)
+	}
+
+	return path, nil
+}
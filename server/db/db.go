@@ -3,6 +3,8 @@ package db
 import (
 	"context"
 	_ "embed"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
@@ -19,6 +21,14 @@ var schema string
 
 type DB struct {
 	pool *pgxpool.Pool
+	// quota is how many objects one owner may have. Zero means no limit.
+	quota int
+}
+
+// SetObjectQuota limits how many objects Save will let any one owner have.
+// Zero, the default, means no limit.
+func (db *DB) SetObjectQuota(n int) {
+	db.quota = n
 }
 
 func Connect() (*pgx.Conn, error) {
@@ -30,8 +40,10 @@ func Connect() (*pgx.Conn, error) {
 	return conn, nil
 }
 
-func Pool() (*pgxpool.Pool, error) {
-	pool, err := pgxpool.New(context.Background(), "")
+// Pool connects to the database described by dsn. An empty dsn falls back on
+// the PG* environment variables.
+func Pool(dsn string) (*pgxpool.Pool, error) {
+	pool, err := pgxpool.New(context.Background(), dsn)
 	if err != nil {
 		return nil, fmt.Errorf("Unable to connect to database: %w", err)
 	}
@@ -55,8 +67,8 @@ func Reset(opts ResetOpts) error {
 	return nil
 }
 
-func NewDB() (*DB, error) {
-	pool, err := Pool()
+func NewDB(dsn string) (*DB, error) {
+	pool, err := Pool(dsn)
 	if err != nil {
 		return nil, err
 	}
@@ -86,8 +98,10 @@ func (db *DB) Erase() (err error) {
 
 // Ensure checks for and then creates default resources if they do not exist (like the Foyer)
 func (db *DB) Ensure() error {
-	// TODO this is sloppy but shrug
-	_, err := db.pool.Exec(context.Background(), schema)
+	if _, err := db.pool.Exec(context.Background(), schema); err != nil {
+		return fmt.Errorf("failed to apply schema: %w", err)
+	}
+
 	u, err := user.Lookup("root")
 	if err != nil {
 		return err
@@ -99,10 +113,11 @@ func (db *DB) Ensure() error {
 
 	rootuid := uint32(uid)
 
-	foyer, err := db.ObjectByOwnerName(rootuid, "foyer")
+	foyer, err := db.seed(rootuid, "foyer", "foyer")
 	if err != nil {
 		// TODO actually check error. for now assuming it means does not exist
 		foyer = NewRoom(rootuid)
+		foyer.Code = "foyer"
 		foyer.SetData("name", "foyer")
 		foyer.SetData("description", "a big room. the ceiling is painted with constellations")
 		if err = foyer.Save(db); err != nil {
@@ -110,10 +125,11 @@ func (db *DB) Ensure() error {
 		}
 	}
 
-	egg, err := db.ObjectByOwnerName(rootuid, "floor egg")
+	egg, err := db.seed(rootuid, "floor-egg", "floor egg")
 	if err != nil {
 		// TODO actually check error. for now assuming it means does not exist
 		egg = NewObject(rootuid)
+		egg.Code = "floor-egg"
 		egg.SetData("name", "floor egg")
 		egg.SetData("description", "it's an egg and it's on the floor")
 		egg.Perms.Carry = PermOwner
@@ -122,10 +138,11 @@ func (db *DB) Ensure() error {
 		}
 	}
 
-	pub, err := db.ObjectByOwnerName(rootuid, "pub")
+	pub, err := db.seed(rootuid, "pub", "pub")
 	if err != nil {
 		// TODO actually check error. for now assuming it means does not exist
 		pub = NewRoom(rootuid)
+		pub.Code = "pub"
 		pub.SetData("name", "pub")
 		pub.SetData("description", "a warm, cozy pub constructed of hard wood and brass")
 		if err = pub.Save(db); err != nil {
@@ -133,39 +150,67 @@ func (db *DB) Ensure() error {
 		}
 	}
 
-	oakDoor, err := db.ObjectByOwnerName(rootuid, "oak door")
-	if err != nil {
-		// TODO actually check error. for now assuming it means does not exist
-		oakDoor = NewObject(rootuid)
+	egg.MoveInto(db, *foyer)
+
+	// the oak door between the foyer and the pub is an exit. databases from
+	// before exits have a door on each side instead, which are replaced.
+	if _, err = db.ExitFrom(*foyer, "north"); err != nil {
+		old := []int{}
+		if o, err := db.seed(rootuid, "oak-door", "oak door"); err == nil {
+			old = append(old, o.ID)
+		}
+		if o, err := db.seed(rootuid, "oak-door-out", "oak door out"); err == nil {
+			old = append(old, o.ID)
+		}
+		if _, err = db.pool.Exec(context.Background(), "DELETE FROM objects WHERE id = ANY($1)", old); err != nil {
+			return err
+		}
+
+		oakDoor := NewObject(rootuid)
+		oakDoor.Code = "oak-door"
 		oakDoor.SetData("name", "oak door")
-		oakDoor.SetData("description", "a heavy oak door with a brass handle. an ornate sign says PUB.")
-		oakDoor.AppendScript(fmt.Sprintf("goes(north, %d)", pub.ID))
+		oakDoor.SetData("description", "a heavy oak door with a brass handle. an ornate sign says PUB on one side and EXIT on the other.")
 		oakDoor.Perms.Carry = PermOwner
-		if err = oakDoor.Save(db); err != nil {
+
+		e, err := db.Link(oakDoor, *foyer, *pub, "north", "south")
+		if err != nil {
 			return err
 		}
-	}
-
-	revOakDoor, err := db.ObjectByOwnerName(rootuid, "oak door out")
-	if err != nil {
-		// TODO actually check error. for now assuming it means does not exist
-		revOakDoor = NewObject(rootuid)
-		revOakDoor.SetData("name", "oak door out")
-		revOakDoor.SetData("description", "a heavy oak door with a brass handle. an ornate sign says EXIT.")
-		revOakDoor.AppendScript(fmt.Sprintf("goes(south, %d)", foyer.ID))
-		revOakDoor.Perms.Carry = PermOwner
-		if err = revOakDoor.Save(db); err != nil {
+		if err = e.Twin.SetCode(db, "oak-door-out"); err != nil {
+			return err
+		}
+		if err = e.Door.MoveInto(db, *foyer); err != nil {
+			return err
+		}
+		if err = e.Twin.MoveInto(db, *pub); err != nil {
 			return err
 		}
 	}
 
-	egg.MoveInto(db, *foyer)
-	oakDoor.MoveInto(db, *foyer)
-	revOakDoor.MoveInto(db, *pub)
-
 	return nil
 }
 
+// seed finds the root owned object with code for Ensure. Objects made before
+// there were codes only have their name to go by, so the oldest such object
+// called name is given code first.
+func (db *DB) seed(rootuid uint32, code, name string) (*Object, error) {
+	if o, err := db.ObjectByCode(rootuid, code); err == nil {
+		return o, nil
+	}
+
+	stmt := `
+		UPDATE objects SET code = $2
+		WHERE id = (
+			SELECT id FROM objects
+			WHERE owneruid = $1 AND code IS NULL AND data['name'] = $3
+			ORDER BY id LIMIT 1)`
+	if _, err := db.pool.Exec(context.Background(), stmt, rootuid, code, jsonString(name)); err != nil {
+		return nil, err
+	}
+
+	return db.ObjectByCode(rootuid, code)
+}
+
 func (db *DB) GreateAvatar(uid uint32, name string) (av *Object, err error) {
 	av, err = db.GetAvatarForUid(uid)
 	// TODO actually check error. for now assuming it means does not exist
@@ -207,9 +252,17 @@ func (db *DB) GetAvatarForUid(uid uint32) (av *Object, err error) {
 	return
 }
 
+const rememberWhereabouts = `
+	INSERT INTO whereabouts (avatar, lastroom)
+	SELECT contained, container FROM contains WHERE contained = $1
+	ON CONFLICT (avatar) DO UPDATE SET lastroom = EXCLUDED.lastroom`
+
 func (db *DB) Derez(uid uint32) (err error) {
 	var o *Object
 	if o, err = db.GetAvatarForUid(uid); err == nil {
+		if _, err = db.pool.Exec(context.Background(), rememberWhereabouts, o.ID); err != nil {
+			log.Printf("failed to remember where avatar %d was: %s", o.ID, err.Error())
+		}
 		if _, err = db.pool.Exec(context.Background(),
 			"DELETE FROM contains WHERE contained = $1", o.ID); err != nil {
 			log.Printf("failed to remove avatar from room: %s", err.Error())
@@ -221,6 +274,89 @@ func (db *DB) Derez(uid uint32) (err error) {
 	return
 }
 
+func (db *DB) BedroomForUid(uid uint32) (*Object, error) {
+	var oid int
+	stmt := "SELECT id FROM objects WHERE bedroom = true AND owneruid = $1 ORDER BY id LIMIT 1"
+	if err := db.pool.QueryRow(context.Background(), stmt, uid).Scan(&oid); err != nil {
+		return nil, err
+	}
+
+	return db.ObjectByID(oid)
+}
+
+// Home returns the room an avatar has chosen with SetHome, falling back to its
+// owner's bedroom.
+func (db *DB) Home(av Object) (*Object, error) {
+	var home *int
+	stmt := "SELECT home FROM whereabouts WHERE avatar = $1"
+	err := db.pool.QueryRow(context.Background(), stmt, av.ID).Scan(&home)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	if home != nil {
+		return db.ObjectByID(*home)
+	}
+
+	return db.BedroomForUid(uint32(av.OwnerID))
+}
+
+// ErrNotPermitted is returned when an avatar's owner isn't allowed to do
+// something to an object.
+var ErrNotPermitted = errors.New("not permitted")
+
+// SetHome makes room av's home. Only rooms av's owner could carry things into
+// (see CarryableBy) can be a home.
+func (db *DB) SetHome(av, room Object) error {
+	if !room.CarryableBy(av.OwnerID) {
+		return ErrNotPermitted
+	}
+
+	stmt := `
+		INSERT INTO whereabouts (avatar, home) VALUES ($1, $2)
+		ON CONFLICT (avatar) DO UPDATE SET home = EXCLUDED.home`
+	_, err := db.pool.Exec(context.Background(), stmt, av.ID, room.ID)
+	return err
+}
+
+// Hidden reports whether an avatar's owner has asked to keep their
+// whereabouts private.
+func (db *DB) Hidden(av Object) (bool, error) {
+	var hidden bool
+	stmt := "SELECT hidden FROM whereabouts WHERE avatar = $1"
+	err := db.pool.QueryRow(context.Background(), stmt, av.ID).Scan(&hidden)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return false, err
+	}
+
+	return hidden, nil
+}
+
+func (db *DB) SetHidden(av Object, hidden bool) error {
+	stmt := `
+		INSERT INTO whereabouts (avatar, hidden) VALUES ($1, $2)
+		ON CONFLICT (avatar) DO UPDATE SET hidden = EXCLUDED.hidden`
+	_, err := db.pool.Exec(context.Background(), stmt, av.ID, hidden)
+	return err
+}
+
+// LastRoom returns the room an avatar was in when it last derezzed. Avatars
+// that have never derezzed (or whose last room is gone) wake up at Home.
+func (db *DB) LastRoom(av Object) (*Object, error) {
+	var lastroom *int
+	stmt := "SELECT lastroom FROM whereabouts WHERE avatar = $1"
+	err := db.pool.QueryRow(context.Background(), stmt, av.ID).Scan(&lastroom)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	if lastroom != nil {
+		return db.ObjectByID(*lastroom)
+	}
+
+	return db.Home(av)
+}
+
 func (db *DB) GetObjectByID(ID int) (*Object, error) {
 	ctx := context.Background()
 	obj := &Object{}
@@ -233,17 +369,9 @@ func (db *DB) GetObjectByID(ID int) (*Object, error) {
 	return obj, err
 }
 
-func (db *DB) GetObject(owneruid uint32, name string) (obj *Object, err error) {
-	ctx := context.Background()
-	obj = &Object{}
-	stmt := `
-		SELECT id, avatar, data, owneruid, script
-		FROM objects
-		WHERE owneruid = $1 AND data['name'] = $2`
-	err = db.pool.QueryRow(ctx, stmt, owneruid, fmt.Sprintf(`"%s"`, name)).Scan(
-		&obj.ID, &obj.Avatar, &obj.Data, &obj.OwnerID, &obj.script)
-
-	return
+// GetObject is ObjectByOwnerName.
+func (db *DB) GetObject(owneruid uint32, name string) (*Object, error) {
+	return db.ObjectByOwnerName(owneruid, name)
 }
 
 func (db *DB) SearchObjectsByName(term string) ([]Object, error) {
@@ -283,19 +411,100 @@ func (db *DB) Resolve(vantage Object, term string) ([]Object, error) {
 		return nil, err
 	}
 
+	matched, err := db.Match(vantage, stuff, term)
+	if err != nil {
+		return nil, err
+	}
+
 	out := []Object{}
 
-	for _, o := range stuff {
-		if strings.Contains(o.Data["name"], term) {
-			out = append(out, *o)
-		}
+	for _, o := range matched {
+		out = append(out, *o)
 	}
 
 	return out, nil
 }
 
+// Match narrows candidates down to what term could mean from vantage's point
+// of view. On top of what Filter understands:
+//
+//   - "me" and "here" mean vantage and its container, if they are candidates
+//   - an exact name (ignoring case) wins over names that merely contain term
+//   - "2.orb" means the second candidate that "orb" matches
+//   - "vilmibm/orb" means the candidate with that ref (see ObjectByRef)
+func (db *DB) Match(vantage Object, candidates []*Object, term string) ([]*Object, error) {
+	term = strings.TrimSpace(term)
+
+	want := -1
+	switch term {
+	case "me":
+		want = vantage.ID
+	case "here":
+		room, err := vantage.Container(db)
+		if err != nil {
+			return nil, err
+		}
+		want = room.ID
+	}
+
+	if want < 0 && strings.Contains(term, "/") {
+		if o, err := db.ObjectByRef(term); err == nil {
+			want = o.ID
+		}
+	}
+
+	if want >= 0 {
+		for _, o := range candidates {
+			if o.ID == want {
+				return []*Object{o}, nil
+			}
+		}
+		return []*Object{}, nil
+	}
+
+	nth := 0
+	if prefix, rest, ok := strings.Cut(term, "."); ok {
+		if n, err := strconv.Atoi(prefix); err == nil && n > 0 {
+			nth = n
+			term = rest
+		}
+	}
+
+	matched := Filter(candidates, term)
+
+	exact := []*Object{}
+	for _, o := range matched {
+		if strings.EqualFold(o.GetData("name"), term) {
+			exact = append(exact, o)
+		}
+	}
+	if len(exact) > 0 {
+		matched = exact
+	}
+
+	if nth > 0 {
+		if nth > len(matched) {
+			return []*Object{}, nil
+		}
+		return []*Object{matched[nth-1]}, nil
+	}
+
+	return matched, nil
+}
+
 func (db *DB) GhostBust() error {
-	stmt := "DELETE FROM contains WHERE contained IN (SELECT id FROM objects WHERE objects.avatar)"
+	// ghosts are left behind when the server goes down without derezzing its
+	// avatars, so note where they were before sweeping them out.
+	stmt := `
+		INSERT INTO whereabouts (avatar, lastroom)
+		SELECT contained, container FROM contains
+		WHERE contained IN (SELECT id FROM objects WHERE objects.avatar)
+		ON CONFLICT (avatar) DO UPDATE SET lastroom = EXCLUDED.lastroom`
+	if _, err := db.pool.Exec(context.Background(), stmt); err != nil {
+		return fmt.Errorf("failed to remember where ghosts were: %w", err)
+	}
+
+	stmt = "DELETE FROM contains WHERE contained IN (SELECT id FROM objects WHERE objects.avatar)"
 	if _, err := db.pool.Exec(context.Background(), stmt); err != nil {
 		return fmt.Errorf("failed to bust ghosts: %w", err)
 	}
@@ -308,25 +517,135 @@ func (db *DB) ObjectByID(id int) (*Object, error) {
 	return o, err
 }
 
+// ObjectByOwnerName finds the object ownerid owns called name. Names aren't
+// unique, so it is an error for there to be more than one.
 func (db *DB) ObjectByOwnerName(ownerid uint32, name string) (*Object, error) {
-	ctx := context.Background()
+	s := "SELECT id FROM objects WHERE owneruid = $1 AND data['name'] = $2 LIMIT 2"
+	rows, err := db.pool.Query(context.Background(), s, ownerid, jsonString(name))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	switch len(ids) {
+	case 0:
+		return nil, pgx.ErrNoRows
+	case 1:
+		return db.ObjectByID(ids[0])
+	}
+
+	return nil, fmt.Errorf("%d owns more than one thing called '%s'", ownerid, name)
+}
+
+func (db *DB) ObjectByCode(ownerid uint32, code string) (*Object, error) {
 	var oid int
-	s := "SELECT id FROM objects WHERE owneruid = $1 AND data['name'] = $2"
-	if err := db.pool.QueryRow(ctx, s, ownerid, fmt.Sprintf(`"%s"`, name)).Scan(&oid); err != nil {
+	s := "SELECT id FROM objects WHERE owneruid = $1 AND code = $2"
+	if err := db.pool.QueryRow(context.Background(), s, ownerid, code).Scan(&oid); err != nil {
 		return nil, err
 	}
 
 	return db.ObjectByID(oid)
 }
 
-func hasInvocation(obj *Object) string {
-	hi := "has({\n"
-	for k, v := range obj.Data {
-		hi += fmt.Sprintf(`%s = "%s",`, k, v) + "\n"
+// CountObjects returns how many objects owneruid owns.
+func (db *DB) CountObjects(owneruid uint32) (int, error) {
+	var count int
+	stmt := "SELECT count(*) FROM objects WHERE owneruid = $1"
+	err := db.pool.QueryRow(context.Background(), stmt, owneruid).Scan(&count)
+	return count, err
+}
+
+// ObjectByRef finds an object from a reference a user or script might
+// write: an ID like "42" or an owner scoped code like "vilmibm/pub". The
+// owner may be written ~vilmibm, or as a uid for owners without an account
+// (see RefFor). If the owner has nothing with that code but exactly one thing
+// with that name, that's used instead.
+func (db *DB) ObjectByRef(ref string) (*Object, error) {
+	ref = strings.TrimSpace(ref)
+
+	if id, err := strconv.Atoi(ref); err == nil {
+		return db.ObjectByID(id)
+	}
+
+	owner, name, ok := strings.Cut(strings.TrimPrefix(ref, "~"), "/")
+	if !ok || owner == "" || name == "" {
+		return nil, fmt.Errorf("'%s' is neither an ID nor owner/code", ref)
+	}
+
+	uid, err := ownerUID(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := db.ObjectByCode(uid, name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.ObjectByOwnerName(uid, name)
+	}
+
+	return o, err
+}
+
+// ownerUID is the uid of the owner named in a ref: a username, or failing
+// that a uid.
+func ownerUID(owner string) (uint32, error) {
+	u, err := user.Lookup(owner)
+	if err == nil {
+		owner = u.Uid
+	}
+
+	uid, perr := strconv.ParseUint(owner, 10, 32)
+	if perr != nil {
+		if err != nil {
+			return 0, err
+		}
+		return 0, perr
+	}
+
+	return uint32(uid), nil
+}
+
+// refOwner is how RefFor names the owner with uid: their username, or the
+// uid itself if they don't have one any more.
+func refOwner(uid int) string {
+	owner := strconv.Itoa(uid)
+	if u, err := user.LookupId(owner); err == nil {
+		owner = u.Username
+	}
+
+	return owner
+}
+
+// Foyer is where people who have nowhere else to be end up.
+func (db *DB) Foyer() (*Object, error) {
+	return db.ObjectByRef("root/foyer")
+}
+
+// jsonString is s as a JSON value, for comparing against jsonb fields.
+func jsonString(s string) string {
+	bs, _ := json.Marshal(s)
+	return string(bs)
+}
+
+// RefFor is how to refer to o with ObjectByRef: owner/code if it has a code
+// and its ID otherwise.
+func (db *DB) RefFor(o Object) string {
+	if o.Code == "" {
+		return strconv.Itoa(o.ID)
 	}
-	hi += "})"
 
-	return hi
+	return refOwner(o.OwnerID) + "/" + o.Code
 }
 
 func randSmell() string {
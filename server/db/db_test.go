@@ -0,0 +1,37 @@
+package db
+
+import "testing"
+
+func TestRefOwner(t *testing.T) {
+	tests := []struct {
+		name string
+		uid  int
+		want string
+	}{
+		{name: "has an account", uid: 0, want: "root"},
+		// nobody has this uid, like the owner of something left behind by
+		// a deleted account
+		{name: "no account", uid: 3999999, want: "3999999"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner := refOwner(tt.uid)
+			if owner != tt.want {
+				t.Errorf("refOwner(%d) = %q, want %q", tt.uid, owner, tt.want)
+			}
+
+			uid, err := ownerUID(owner)
+			if err != nil {
+				t.Fatalf("ownerUID(%q) failed: %s", owner, err)
+			}
+			if int(uid) != tt.uid {
+				t.Errorf("ownerUID(%q) = %d, want %d", owner, uid, tt.uid)
+			}
+		})
+	}
+
+	if _, err := ownerUID("no such user"); err == nil {
+		t.Error("ownerUID found an owner that doesn't exist")
+	}
+}
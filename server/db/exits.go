@@ -0,0 +1,214 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Exit is a pair of doors between two rooms. Door is in Here and leads
+// Direction to There; Twin is in There and leads Reverse back. Both doors
+// have the same data and script apart from the goes() that takes people
+// through.
+type Exit struct {
+	ID        int
+	Door      *Object
+	Twin      *Object
+	Here      int
+	There     int
+	Direction string
+	Reverse   string
+}
+
+func exitScript(direction string, to int, shared string) string {
+	return fmt.Sprintf("goes(%s, %d)\n%s", direction, to, shared)
+}
+
+// sharedScript is the part of an exit door's script that both sides run, as
+// given to exitScript.
+func sharedScript(script string) string {
+	_, shared, _ := strings.Cut(script, "\n")
+	return shared
+}
+
+// Link makes door, which shouldn't have been saved yet, and a twin of it into
+// an exit between here and there. door's script is what both sides run.
+// Both doors and the exit are saved together or not at all. Putting the
+// doors in their rooms is left to the caller.
+func (db *DB) Link(door *Object, here, there Object, direction, reverse string) (*Exit, error) {
+	return db.makeExit(door, here, &there, false, direction, reverse)
+}
+
+// Dig is Link to a new room, there, which is saved along with the doors so
+// that a dig that can't finish leaves nothing behind.
+func (db *DB) Dig(door *Object, here Object, there *Object, direction, reverse string) (*Exit, error) {
+	return db.makeExit(door, here, there, true, direction, reverse)
+}
+
+// makeExit does Link and Dig. When dig is set there is saved first.
+func (db *DB) makeExit(door *Object, here Object, there *Object, dig bool, direction, reverse string) (*Exit, error) {
+	shared := door.GetScript()
+
+	twin := NewObject(uint32(door.OwnerID))
+	twin.Data = maps.Clone(door.Data)
+	if door.Perms != nil {
+		perms := *door.Perms
+		twin.Perms = &perms
+	}
+
+	ctx := context.Background()
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if dig {
+		if err = there.insert(ctx, db, tx); err != nil {
+			return nil, err
+		}
+	}
+
+	// there has an ID now
+	door.SetScript(exitScript(direction, there.ID, shared))
+	twin.SetScript(exitScript(reverse, here.ID, shared))
+
+	if err = door.insert(ctx, db, tx); err != nil {
+		return nil, err
+	}
+	if err = twin.insert(ctx, db, tx); err != nil {
+		return nil, err
+	}
+
+	e := &Exit{
+		Door:      door,
+		Twin:      twin,
+		Here:      here.ID,
+		There:     there.ID,
+		Direction: direction,
+		Reverse:   reverse,
+	}
+
+	stmt := `
+		INSERT INTO exits (door, twin, here, there, direction, reverse)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`
+	err = tx.QueryRow(ctx, stmt,
+		door.ID, twin.ID, here.ID, there.ID, direction, reverse).Scan(&e.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, tx.Commit(ctx)
+}
+
+// exitRow is a row of the exits table.
+type exitRow struct {
+	id, door, twin, here, there int
+	direction, reverse          string
+}
+
+// facing is r turned around, if need be, so that door is the side in room.
+func (r exitRow) facing(room int) exitRow {
+	if r.there != room {
+		return r
+	}
+
+	r.door, r.twin = r.twin, r.door
+	r.here, r.there = r.there, r.here
+	r.direction, r.reverse = r.reverse, r.direction
+
+	return r
+}
+
+// exit loads the doors of r.
+func (r exitRow) exit(db *DB) (*Exit, error) {
+	e := &Exit{
+		ID:        r.id,
+		Here:      r.here,
+		There:     r.there,
+		Direction: r.direction,
+		Reverse:   r.reverse,
+	}
+
+	var err error
+	if e.Door, err = db.ObjectByID(r.door); err != nil {
+		return nil, err
+	}
+	if e.Twin, err = db.ObjectByID(r.twin); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+const exitColumns = "id, door, twin, here, there, direction, reverse"
+
+func (r *exitRow) scan(row pgx.Row) error {
+	return row.Scan(&r.id, &r.door, &r.twin, &r.here, &r.there, &r.direction, &r.reverse)
+}
+
+// ExitFrom finds the exit leading direction out of room. The returned Exit
+// is turned around if need be so that Door is the side in room.
+func (db *DB) ExitFrom(room Object, direction string) (*Exit, error) {
+	var r exitRow
+	stmt := `
+		SELECT ` + exitColumns + ` FROM exits
+		WHERE (here = $1 AND direction = $2) OR (there = $1 AND reverse = $2)
+		LIMIT 1`
+	if err := r.scan(db.pool.QueryRow(context.Background(), stmt, room.ID, direction)); err != nil {
+		return nil, err
+	}
+
+	return r.facing(room.ID).exit(db)
+}
+
+// ExitOf finds the exit door is one side of, turned around if need be so
+// that door is its Door. It returns pgx.ErrNoRows for anything that isn't
+// an exit's door.
+func (db *DB) ExitOf(door Object) (*Exit, error) {
+	var r exitRow
+	stmt := `SELECT ` + exitColumns + ` FROM exits WHERE door = $1 OR twin = $1`
+	if err := r.scan(db.pool.QueryRow(context.Background(), stmt, door.ID)); err != nil {
+		return nil, err
+	}
+
+	// the twin is the side in there
+	if r.twin == door.ID {
+		r = r.facing(r.there)
+	}
+
+	return r.exit(db)
+}
+
+// SaveExit writes the data of e's Door, and shared as the script, to both
+// sides of e so that editing one door edits the other. Object.SaveData uses
+// it for doors.
+func (db *DB) SaveExit(e Exit, shared string) error {
+	ctx := context.Background()
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	stmt := "UPDATE objects SET data = $2, script = $3 WHERE id = $1"
+	if _, err = tx.Exec(ctx, stmt, e.Door.ID, e.Door.Data, exitScript(e.Direction, e.There, shared)); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, stmt, e.Twin.ID, e.Door.Data, exitScript(e.Reverse, e.Here, shared)); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Unlink removes both of e's doors from the world. Deleting either door any
+// other way does the same (see schema.sql).
+func (db *DB) Unlink(e Exit) error {
+	_, err := db.pool.Exec(context.Background(), "DELETE FROM exits WHERE id = $1", e.ID)
+	return err
+}
@@ -0,0 +1,48 @@
+package db
+
+import "testing"
+
+func TestExitRowFacing(t *testing.T) {
+	r := exitRow{id: 1, door: 10, twin: 11, here: 20, there: 21, direction: "north", reverse: "south"}
+	turned := exitRow{id: 1, door: 11, twin: 10, here: 21, there: 20, direction: "south", reverse: "north"}
+
+	tests := []struct {
+		name string
+		room int
+		want exitRow
+	}{
+		{name: "from here", room: 20, want: r},
+		{name: "from there", room: 21, want: turned},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.facing(tt.room); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if got := r.facing(21).facing(20); got != r {
+		t.Errorf("turning back around got %+v, want %+v", got, r)
+	}
+}
+
+func TestSharedScript(t *testing.T) {
+	tests := []struct {
+		name   string
+		shared string
+	}{
+		{name: "empty", shared: ""},
+		{name: "one line", shared: `hears("knock", function() tellSender("who's there?") end)`},
+		{name: "several lines", shared: "x = 1\nhas({name = \"gate\"})\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sharedScript(exitScript("north", 42, tt.shared)); got != tt.shared {
+				t.Errorf("got %q, want %q", got, tt.shared)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package db
+
+import "context"
+
+// Ignore stops ignorer hearing shouts from ignored. Both are uids.
+func (db *DB) Ignore(ignorer, ignored uint32) error {
+	stmt := `
+		INSERT INTO ignores (ignorer, ignored) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`
+	_, err := db.pool.Exec(context.Background(), stmt, ignorer, ignored)
+	return err
+}
+
+// Unignore reports whether ignorer had been ignoring ignored.
+func (db *DB) Unignore(ignorer, ignored uint32) (bool, error) {
+	stmt := "DELETE FROM ignores WHERE ignorer = $1 AND ignored = $2"
+	tag, err := db.pool.Exec(context.Background(), stmt, ignorer, ignored)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// Ignoring returns the uids ignorer is ignoring.
+func (db *DB) Ignoring(ignorer uint32) ([]uint32, error) {
+	return db.uids("SELECT ignored FROM ignores WHERE ignorer = $1", ignorer)
+}
+
+// Ignorers returns the uids ignoring ignored.
+func (db *DB) Ignorers(ignored uint32) ([]uint32, error) {
+	return db.uids("SELECT ignorer FROM ignores WHERE ignored = $1", ignored)
+}
+
+func (db *DB) uids(stmt string, uid uint32) ([]uint32, error) {
+	rows, err := db.pool.Query(context.Background(), stmt, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []uint32{}
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, uint32(id))
+	}
+
+	return out, rows.Err()
+}
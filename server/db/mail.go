@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// Mail is a message left for a user. Recipient is a uid since mail can be
+// sent to users who have never connected and so have no avatar yet.
+type Mail struct {
+	ID        int
+	Sender    string
+	Recipient uint32
+	Body      string
+	Sent      time.Time
+	Read      bool
+}
+
+func (db *DB) SendMail(sender string, recipient uint32, body string) error {
+	stmt := "INSERT INTO mail (sender, recipient, body) VALUES ($1, $2, $3)"
+	_, err := db.pool.Exec(context.Background(), stmt, sender, recipient, body)
+	return err
+}
+
+// Mailbox returns uid's mail, oldest first. With unread set only mail that
+// hasn't been read is returned.
+func (db *DB) Mailbox(uid uint32, unread bool) ([]Mail, error) {
+	stmt := `
+		SELECT id, sender, recipient, body, sent, read FROM mail
+		WHERE recipient = $1 AND (NOT $2 OR NOT read)
+		ORDER BY sent, id`
+	rows, err := db.pool.Query(context.Background(), stmt, uid, unread)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Mail{}
+	for rows.Next() {
+		m := Mail{}
+		if err = rows.Scan(&m.ID, &m.Sender, &m.Recipient, &m.Body, &m.Sent, &m.Read); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+
+	return out, rows.Err()
+}
+
+func (db *DB) MarkMailRead(uid uint32, id int) error {
+	stmt := "UPDATE mail SET read = TRUE WHERE recipient = $1 AND id = $2"
+	_, err := db.pool.Exec(context.Background(), stmt, uid, id)
+	return err
+}
+
+// DeleteMail removes a message from uid's mailbox. It reports whether there
+// was such a message.
+func (db *DB) DeleteMail(uid uint32, id int) (bool, error) {
+	stmt := "DELETE FROM mail WHERE recipient = $1 AND id = $2"
+	tag, err := db.pool.Exec(context.Background(), stmt, uid, id)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
@@ -2,9 +2,14 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type Perm string
@@ -20,9 +25,14 @@ type Object struct {
 	Avatar  bool
 	Bedroom bool
 	OwnerID int
-	Perms   *Permissions
-	script  string
-	Data    map[string]string
+	// Code is an optional short name for the object, unique among its owner's
+	// things. See DB.ObjectByRef.
+	Code   string
+	Perms  *Permissions
+	script string
+	// Data holds anything JSON can: strings, float64s, bools, []any and
+	// map[string]any.
+	Data map[string]any
 }
 
 type Permissions struct {
@@ -32,10 +42,21 @@ type Permissions struct {
 	Exec  Perm
 }
 
+// CarryableBy reports whether uid may move o about or, if o is a room or
+// container, move things into it: they own it or its Carry permission is
+// world. Unknown permissions allow nothing.
+func (o Object) CarryableBy(uid int) bool {
+	if o.OwnerID == uid {
+		return true
+	}
+
+	return o.Perms != nil && o.Perms.Carry == PermWorld
+}
+
 func NewObject(owneruid uint32) *Object {
 	o := &Object{
 		OwnerID: int(owneruid),
-		Data:    map[string]string{},
+		Data:    map[string]any{},
 	}
 
 	o.SetData("name", "plain orb")
@@ -98,16 +119,20 @@ func NewRoom(owneruid uint32) *Object {
 	return o
 }
 
-func (o *Object) SetData(key string, value string) {
+func (o *Object) SetData(key string, value any) {
 	o.Data[key] = value
 }
 
+// GetData is the value of key as text, or "" if it isn't set.
 func (o *Object) GetData(key string) string {
-	v, ok := o.Data[key]
-	if !ok {
+	switch v := o.Data[key].(type) {
+	case nil:
 		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
 	}
-	return v
 }
 
 func (o *Object) SetScript(code string) {
@@ -119,34 +144,15 @@ func (o *Object) AppendScript(code string) {
 	o.script += "\n" + code
 }
 
+// GetScript is o's WITCH source. What o has and allows isn't part of it;
+// scripts are given those as values (see witch.NewScriptContext).
 func (o *Object) GetScript() string {
-	return fmt.Sprintf(`
-		%s
-		%s
-		%s
-	`, o.hasInvocation(), o.allowsInvocation(), o.script)
-}
-
-func (o *Object) hasInvocation() string {
-	hi := "has({\n"
-	for k, v := range o.Data {
-		hi += fmt.Sprintf(`  %s = "%s",`, k, v) + "\n"
-	}
-	hi += "})"
-
-	return hi
-}
-
-func (o *Object) allowsInvocation() string {
-	return fmt.Sprintf(`
-allows({
-	read = "%s",
-	write = "%s",
-	carry = "%s",
-	execute = "%s",
-})`, o.Perms.Read, o.Perms.Write, o.Perms.Carry, o.Perms.Exec)
+	return o.script
 }
 
+// Save inserts o, which shouldn't have been saved yet, along with its
+// permissions. It returns ErrQuota if o's owner already owns as many objects
+// as the DB's object quota allows.
 func (o *Object) Save(db *DB) error {
 	ctx := context.Background()
 	tx, err := db.pool.Begin(ctx)
@@ -155,33 +161,102 @@ func (o *Object) Save(db *DB) error {
 	}
 	defer tx.Rollback(ctx)
 
+	if err = o.insert(ctx, db, tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// insert does Save's work inside tx.
+func (o *Object) insert(ctx context.Context, db *DB, tx pgx.Tx) error {
+	// holding a lock on the owner until tx ends keeps two inserts from both
+	// squeezing in under the quota
+	if db.quota > 0 {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", o.OwnerID); err != nil {
+			return err
+		}
+	}
+
 	stmt := `
-		INSERT INTO objects (avatar, bedroom, data, script, owneruid)
-		VALUES ( $1, $2, $3, $4, $5)
+		INSERT INTO objects (avatar, bedroom, data, script, owneruid, code)
+		SELECT $1, $2, $3, $4, $5, NULLIF($6, '')
+		WHERE $7 <= 0 OR (SELECT count(*) FROM objects WHERE owneruid = $5) < $7
 		RETURNING id`
-	err = db.pool.QueryRow(ctx, stmt,
-		o.Avatar, o.Bedroom, o.Data, o.script, o.OwnerID).Scan(
+	err := tx.QueryRow(ctx, stmt,
+		o.Avatar, o.Bedroom, o.Data, o.script, o.OwnerID, o.Code, db.quota).Scan(
 		&o.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrQuota
+	}
 	if err != nil {
 		return err
 	}
 
 	stmt = `INSERT INTO permissions (object, read, write, carry, exec)
 					VALUES ($1, $2, $3, $4, $5)`
-	if _, err = tx.Exec(ctx, stmt, o.ID,
-		o.Perms.Read, o.Perms.Write, o.Perms.Carry, o.Perms.Exec); err != nil {
+	_, err = tx.Exec(ctx, stmt, o.ID,
+		o.Perms.Read, o.Perms.Write, o.Perms.Carry, o.Perms.Exec)
+
+	return err
+}
+
+// SaveData writes o's data over what was saved for it. If o is one side of an
+// exit the other side gets the same data.
+func (o *Object) SaveData(db *DB) error {
+	e, err := db.ExitOf(*o)
+	if err == nil {
+		e.Door.Data = o.Data
+		return db.SaveExit(*e, sharedScript(e.Door.GetScript()))
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
 		return err
 	}
 
-	return tx.Commit(ctx)
+	stmt := "UPDATE objects SET data = $2 WHERE id = $1"
+	_, err = db.pool.Exec(context.Background(), stmt, o.ID, o.Data)
+	return err
+}
+
+var (
+	ErrQuota       = errors.New("object quota reached")
+	ErrCodeTaken   = errors.New("the owner already has something with that code")
+	ErrInvalidCode = errors.New("codes are lowercase letters, numbers, - and _")
+)
+
+// uniqueViolation is postgres' error code for a broken UNIQUE constraint.
+const uniqueViolation = "23505"
+
+var validCode = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// SetCode gives o a new short code. An empty code removes it.
+func (o *Object) SetCode(db *DB, code string) error {
+	if code != "" && !validCode.MatchString(code) {
+		return ErrInvalidCode
+	}
+
+	stmt := "UPDATE objects SET code = NULLIF($2, '') WHERE id = $1"
+	_, err := db.pool.Exec(context.Background(), stmt, o.ID, code)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return ErrCodeTaken
+	}
+	if err != nil {
+		return err
+	}
+
+	o.Code = code
+
+	return nil
 }
 
 func (o *Object) Refresh(db *DB) error {
-	s := `SELECT avatar, data, owneruid, script FROM objects WHERE id = $1`
+	s := `SELECT avatar, data, owneruid, script, COALESCE(code, '') FROM objects WHERE id = $1`
 	ctx := context.Background()
 
 	err := db.pool.QueryRow(ctx, s, o.ID).Scan(
-		&o.Avatar, &o.Data, &o.OwnerID, &o.script)
+		&o.Avatar, &o.Data, &o.OwnerID, &o.script, &o.Code)
 
 	if err != nil {
 		return err
@@ -211,12 +286,16 @@ func (o *Object) Container(db *DB) (*Object, error) {
 	return db.ObjectByID(containerID)
 }
 
+// Earshot is everything in the room o is in, and the room itself, oldest
+// first. The order stays the same between calls so that ordinals like 2.orb
+// (see Match) keep meaning the same thing.
 func (o *Object) Earshot(db *DB) ([]*Object, error) {
 	stmt := `
 	SELECT id FROM objects WHERE
 		id IN (SELECT contained FROM contains WHERE container = (
 						SELECT container FROM contains WHERE contained = $1 LIMIT 1))
-		OR id = (SELECT container FROM contains WHERE contained = $1 LIMIT 1)`
+		OR id = (SELECT container FROM contains WHERE contained = $1 LIMIT 1)
+	ORDER BY id`
 	rows, err := db.pool.Query(context.Background(), stmt, o.ID)
 	if err != nil {
 		return nil, err
@@ -251,8 +330,9 @@ func Filter(os []*Object, term string) []*Object {
 			}
 		}
 	} else {
+		term = strings.ToLower(term)
 		for _, o := range os {
-			if strings.Contains(o.Data["name"], term) {
+			if strings.Contains(strings.ToLower(o.GetData("name")), term) {
 				out = append(out, o)
 			}
 		}
@@ -284,8 +364,9 @@ func (o *Object) MoveInto(db *DB, container Object) error {
 	return tx.Commit(ctx)
 }
 
+// Contents is everything directly inside o, oldest first, like Earshot.
 func (o *Object) Contents(db *DB) ([]*Object, error) {
-	stmt := `SELECT contained FROM contains WHERE container = $1`
+	stmt := `SELECT contained FROM contains WHERE container = $1 ORDER BY contained`
 	rows, err := db.pool.Query(context.Background(), stmt, o.ID)
 	if err != nil {
 		return nil, err
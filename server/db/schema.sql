@@ -1,16 +1,30 @@
-CREATE TYPE perm AS ENUM ('owner', 'world');
+-- this is run every time the server starts (see DB.Ensure), so everything
+-- in it has to be fine to run again against a database it already set up.
+-- columns added since a table was first made get an ALTER TABLE as well.
 
-CREATE TABLE objects (
+DO $$
+BEGIN
+  CREATE TYPE perm AS ENUM ('owner', 'world');
+EXCEPTION
+  WHEN duplicate_object THEN NULL;
+END;
+$$;
+
+CREATE TABLE IF NOT EXISTS objects (
   id        serial  PRIMARY KEY,
   owneruid  int,
   avatar    boolean NOT NULL DEFAULT FALSE,
   bedroom   boolean NOT NULL DEFAULT FALSE,
   data      jsonb   NOT NULL,
-  script    text    NOT NULL
+  script    text    NOT NULL,
+  code      text
 );
 
+ALTER TABLE objects ADD COLUMN IF NOT EXISTS code text;
+CREATE UNIQUE INDEX IF NOT EXISTS objects_owneruid_code ON objects (owneruid, code);
+
 -- owner = 1, world = 2
-CREATE TABLE permissions (
+CREATE TABLE IF NOT EXISTS permissions (
   id    serial  PRIMARY KEY,
   read  perm    NOT NULL DEFAULT 'world',
   write perm    NOT NULL DEFAULT 'owner',
@@ -20,7 +34,68 @@ CREATE TABLE permissions (
   object integer REFERENCES objects ON DELETE CASCADE
 );
 
-CREATE TABLE contains (
+CREATE TABLE IF NOT EXISTS contains (
   container integer REFERENCES objects ON DELETE RESTRICT,
   contained integer REFERENCES objects ON DELETE CASCADE
 );
+
+CREATE TABLE IF NOT EXISTS whereabouts (
+  avatar   integer PRIMARY KEY REFERENCES objects ON DELETE CASCADE,
+  lastroom integer REFERENCES objects ON DELETE SET NULL,
+  home     integer REFERENCES objects ON DELETE SET NULL,
+  hidden   boolean NOT NULL DEFAULT FALSE
+);
+
+ALTER TABLE whereabouts ADD COLUMN IF NOT EXISTS hidden boolean NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS mail (
+  id        serial      PRIMARY KEY,
+  sender    text        NOT NULL,
+  recipient int         NOT NULL,
+  body      text        NOT NULL,
+  sent      timestamptz NOT NULL DEFAULT NOW(),
+  read      boolean     NOT NULL DEFAULT FALSE
+);
+
+-- sent started out as a plain timestamp. the old values were written with
+-- NOW() in the server's time zone, which is how they are read back here.
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM information_schema.columns
+             WHERE table_schema = current_schema() AND table_name = 'mail'
+               AND column_name = 'sent'
+               AND data_type = 'timestamp without time zone') THEN
+    ALTER TABLE mail ALTER COLUMN sent TYPE timestamptz;
+  END IF;
+END;
+$$;
+
+CREATE TABLE IF NOT EXISTS ignores (
+  ignorer int NOT NULL,
+  ignored int NOT NULL,
+  PRIMARY KEY (ignorer, ignored)
+);
+
+-- an exit is a pair of doors, door in here and twin in there, that lead to
+-- each other's room
+CREATE TABLE IF NOT EXISTS exits (
+  id        serial  PRIMARY KEY,
+  door      integer NOT NULL REFERENCES objects ON DELETE CASCADE,
+  twin      integer NOT NULL REFERENCES objects ON DELETE CASCADE,
+  here      integer NOT NULL REFERENCES objects ON DELETE CASCADE,
+  there     integer NOT NULL REFERENCES objects ON DELETE CASCADE,
+  direction text    NOT NULL,
+  reverse   text    NOT NULL
+);
+
+-- losing either door, or either room, loses both doors
+CREATE OR REPLACE FUNCTION delete_exit_doors() RETURNS trigger AS $$
+BEGIN
+  DELETE FROM objects WHERE id IN (OLD.door, OLD.twin);
+  RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS exits_delete_doors ON exits;
+CREATE TRIGGER exits_delete_doors AFTER DELETE ON exits
+  FOR EACH ROW EXECUTE FUNCTION delete_exit_doors();
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vilmibm/hermeticum/proto"
+	"github.com/vilmibm/hermeticum/server/db"
+	"github.com/vilmibm/hermeticum/server/witch"
+)
+
+func init() {
+	registerVerb(&verb{
+		Name:      "link",
+		Usage:     "<direction> <room>",
+		Help:      "make a door in direction leading to an existing room, with one back from there. room is an ID or owner/code. both rooms need to be yours, or let anyone put things in them.",
+		RateLimit: 5 * time.Second,
+		Handler:   (*gameWorldServer).handleLink,
+	})
+	registerVerb(&verb{
+		Name:    "unlink",
+		Usage:   "<direction>",
+		Help:    "remove the door leading direction from here, and its twin on the other side.",
+		Handler: (*gameWorldServer).handleUnlink,
+	})
+}
+
+// exitTaken reports whether room already has a way out heading.
+type exitTaken func(room db.Object, heading string) bool
+
+func (s *gameWorldServer) exitTaken(room db.Object, heading string) bool {
+	_, err := s.db.ExitFrom(room, heading)
+	return err == nil
+}
+
+// digRefusal is why dir can't be dug from here, or "" if it can.
+func digRefusal(here db.Object, dir witch.Direction, taken exitTaken) string {
+	if taken(here, dir.Human()) {
+		return fmt.Sprintf("there's already a way %s from here.", dir.Human())
+	}
+
+	return ""
+}
+
+// linkRefusal is why the user uid can't link here to there with an exit
+// leading dir, or "" if they can.
+func linkRefusal(uid int, here, there db.Object, dir witch.Direction, taken exitTaken) string {
+	if there.ID == here.ID {
+		return "you're already here."
+	}
+
+	// a door goes in each room, the same as putting something down there
+	for _, room := range []db.Object{here, there} {
+		if !room.CarryableBy(uid) {
+			return fmt.Sprintf("%s isn't yours to put a door in.", room.String())
+		}
+	}
+
+	if taken(here, dir.Human()) {
+		return fmt.Sprintf("there's already a way %s from here.", dir.Human())
+	}
+
+	if taken(there, dir.Reverse().Human()) {
+		return fmt.Sprintf("there's already a way %s from %s.", dir.Reverse().Human(), there.String())
+	}
+
+	return ""
+}
+
+// newDoor is a door for avatar to put in leading dir.
+func newDoor(avatar db.Object, dir witch.Direction) *db.Object {
+	door := db.NewObject(uint32(avatar.OwnerID))
+	door.SetData("name", "small gate")
+	door.SetData("description", "a simple wooden gate on a hinge")
+	if dir.IsVertical() {
+		door.SetData("name", "ladder")
+		door.SetData("description", "a basic wooden ladder. it's a little rickety.")
+	}
+
+	return door
+}
+
+// placeDoors puts e's doors in their rooms.
+func (s *gameWorldServer) placeDoors(e *db.Exit, here, there db.Object) error {
+	if err := s.moveInto(*e.Door, here); err != nil {
+		return err
+	}
+
+	return s.moveInto(*e.Twin, there)
+}
+
+// link puts a pair of doors between here and there, owned by avatar.
+func (s *gameWorldServer) link(avatar db.Object, here, there db.Object, dir witch.Direction) (*db.Exit, error) {
+	e, err := s.db.Link(newDoor(avatar, dir), here, there, dir.Human(), dir.Reverse().Human())
+	if err != nil {
+		return nil, err
+	}
+
+	return e, s.placeDoors(e, here, there)
+}
+
+// dig makes a new room for avatar leading dir from here, with a pair of doors
+// between them. Nothing is made unless all of it can be.
+func (s *gameWorldServer) dig(avatar db.Object, here db.Object, dir witch.Direction) (*db.Object, *db.Exit, error) {
+	room := db.NewRoom(uint32(avatar.OwnerID))
+	e, err := s.db.Dig(newDoor(avatar, dir), here, room, dir.Human(), dir.Reverse().Human())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return room, e, s.placeDoors(e, here, *room)
+}
+
+func (s *gameWorldServer) handleLink(avatar db.Object, cmd *proto.Command) error {
+	heading, ref, _ := strings.Cut(strings.TrimSpace(cmd.Rest), " ")
+	ref = strings.TrimSpace(ref)
+	if !witch.ValidDirection(heading) || ref == "" {
+		s.printTo(avatar, fmt.Sprintf("usage: /link <direction> <room>. valid directions are: %v",
+			witch.Directions()))
+		return nil
+	}
+	dir := witch.NormalizeDirection(heading)
+
+	here, err := avatar.Container(s.db)
+	if err != nil {
+		return err
+	}
+
+	there, err := s.db.ObjectByRef(ref)
+	if err != nil {
+		s.printTo(avatar, fmt.Sprintf("there's no room %s.", ref))
+		return nil
+	}
+
+	// rooms aren't inside anything
+	if _, err = there.Container(s.db); err == nil {
+		s.printTo(avatar, fmt.Sprintf("%s isn't a room.", there.String()))
+		return nil
+	}
+
+	if why := linkRefusal(avatar.OwnerID, *here, *there, dir, s.exitTaken); why != "" {
+		s.printTo(avatar, why)
+		return nil
+	}
+
+	err = s.roomFor(uint32(avatar.OwnerID), 2)
+	if errors.Is(err, db.ErrQuota) {
+		s.printQuota(avatar)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	e, err := s.link(avatar, *here, *there, dir)
+	if errors.Is(err, db.ErrQuota) {
+		s.printQuota(avatar)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s.printTo(avatar, fmt.Sprintf("%s now leads %s to %s.",
+		e.Door.GetData("name"), dir.Human(), there.GetData("name")))
+
+	return nil
+}
+
+func (s *gameWorldServer) handleUnlink(avatar db.Object, cmd *proto.Command) error {
+	heading := strings.TrimSpace(cmd.Rest)
+	if !witch.ValidDirection(heading) {
+		s.printTo(avatar, fmt.Sprintf("usage: /unlink <direction>. valid directions are: %v",
+			witch.Directions()))
+		return nil
+	}
+	dir := witch.NormalizeDirection(heading)
+
+	here, err := avatar.Container(s.db)
+	if err != nil {
+		return err
+	}
+
+	e, err := s.db.ExitFrom(*here, dir.Human())
+	if err != nil {
+		s.printTo(avatar, fmt.Sprintf("there's no way %s from here.", dir.Human()))
+		return nil
+	}
+
+	if e.Door.OwnerID != avatar.OwnerID && here.OwnerID != avatar.OwnerID {
+		s.printTo(avatar, fmt.Sprintf("%s isn't yours to remove.", e.Door.GetData("name")))
+		return nil
+	}
+
+	if err = s.db.Unlink(*e); err != nil {
+		return err
+	}
+
+	// the doors vanished rather than left, so nobody has been shown rooms
+	// without them
+	go func() {
+		for _, id := range []int{e.Here, e.There} {
+			room, err := s.db.ObjectByID(id)
+			if err != nil {
+				continue
+			}
+			os, err := room.Contents(s.db)
+			if err != nil {
+				continue
+			}
+			for _, o := range os {
+				if o.Avatar {
+					s.sendRoom(*o)
+				}
+			}
+		}
+	}()
+
+	s.printTo(avatar, fmt.Sprintf("%s is gone, and so is the way back.", e.Door.GetData("name")))
+
+	return nil
+}
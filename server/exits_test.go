@@ -0,0 +1,227 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/vilmibm/hermeticum/proto"
+	"github.com/vilmibm/hermeticum/server/db"
+	"github.com/vilmibm/hermeticum/server/witch"
+)
+
+// testServer is a server using the database HERMETICUM_TEST_DSN points at,
+// which is erased first. Tests that need one are skipped when it isn't set.
+func testServer(t *testing.T) *gameWorldServer {
+	t.Helper()
+
+	dsn := os.Getenv("HERMETICUM_TEST_DSN")
+	if dsn == "" {
+		t.Skip("set HERMETICUM_TEST_DSN to a database that may be erased to run this")
+	}
+
+	d, err := db.NewDB(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Reset(db.ResetOpts{DB: d}); err != nil {
+		t.Fatal(err)
+	}
+
+	return &gameWorldServer{
+		opts:     DefaultServeOpts(),
+		db:       d,
+		sessions: map[uint32]*userIO{},
+		scripts:  map[int]*witch.ScriptContext{},
+	}
+}
+
+// testSession connects uid as an avatar standing in the foyer, without a
+// client on the other end.
+func testSession(t *testing.T, s *gameWorldServer, uid uint32, name string) (*db.Object, *userIO) {
+	t.Helper()
+
+	avatar, err := s.db.GreateAvatar(uid, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uio := &userIO{
+		avatar:   *avatar,
+		username: name,
+		outbound: make(chan *proto.WorldEvent, outboundQueue),
+		gone:     make(chan struct{}),
+	}
+	s.sessions[uid] = uio
+	t.Cleanup(func() { close(uio.gone) })
+
+	foyer, err := s.db.Foyer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.moveInto(*avatar, *foyer); err != nil {
+		t.Fatal(err)
+	}
+
+	return avatar, uio
+}
+
+// printed is the text of every PRINT waiting for uio's client.
+func printed(uio *userIO) []string {
+	out := []string{}
+	for {
+		select {
+		case ev := <-uio.outbound:
+			if ev.Type == proto.WorldEvent_PRINT {
+				out = append(out, ev.GetText())
+			}
+		default:
+			return out
+		}
+	}
+}
+
+func TestDigTakenDirection(t *testing.T) {
+	s := testServer(t)
+	avatar, uio := testSession(t, s, 1000, "vilmibm")
+
+	if err := s.handleDig(*avatar, &proto.Command{Verb: "dig", Rest: "east"}); err != nil {
+		t.Fatal(err)
+	}
+	printed(uio)
+
+	if err := s.handleDig(*avatar, &proto.Command{Verb: "dig", Rest: "east"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "there's already a way east from here."
+	if got := printed(uio); len(got) != 1 || got[0] != want {
+		t.Errorf("digging east twice printed %q, want %q", got, want)
+	}
+
+	// the exit reads the same from the other side, turned around
+	foyer, err := s.db.Foyer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := s.db.ExitFrom(*foyer, "east")
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := s.db.ExitFrom(db.Object{ID: out.There}, "west")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.ID != out.ID || back.Door.ID != out.Twin.ID || back.There != foyer.ID {
+		t.Errorf("the way back is %+v, want exit %d through door %d to %d",
+			back, out.ID, out.Twin.ID, foyer.ID)
+	}
+}
+
+func TestLinkNeedsBothRooms(t *testing.T) {
+	s := testServer(t)
+	avatar, uio := testSession(t, s, 1000, "vilmibm")
+
+	// the foyer is root's and only lets root put things in it
+	room := db.NewRoom(1000)
+	if err := room.Save(s.db); err != nil {
+		t.Fatal(err)
+	}
+	ref := s.db.RefFor(*room)
+
+	foyer, err := s.db.Foyer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.handleLink(*avatar, &proto.Command{Verb: "link", Rest: "west " + ref}); err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("%s isn't yours to put a door in.", foyer.String())
+	if got := printed(uio); len(got) != 1 || got[0] != want {
+		t.Errorf("linking from the foyer printed %q, want %q", got, want)
+	}
+
+	if _, err = s.db.ExitFrom(*foyer, "west"); err == nil {
+		t.Error("an exit was made anyway")
+	}
+}
+
+// takenExits is an exitTaken for rooms whose ways out are listed by room ID.
+func takenExits(ways map[int][]string) exitTaken {
+	return func(room db.Object, heading string) bool {
+		return slices.Contains(ways[room.ID], heading)
+	}
+}
+
+func TestDigRefusal(t *testing.T) {
+	here := db.NewRoom(1000)
+	here.ID = 1
+
+	tests := []struct {
+		name string
+		dir  string
+		want string
+	}{
+		{name: "free", dir: "east", want: ""},
+		{name: "taken", dir: "north", want: "there's already a way north from here."},
+		{name: "taken, written another way", dir: "up", want: "there's already a way above from here."},
+	}
+
+	taken := takenExits(map[int][]string{1: {"north", "above"}})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := digRefusal(*here, witch.NormalizeDirection(tt.dir), taken); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkRefusal(t *testing.T) {
+	mine := db.NewRoom(1000)
+	mine.ID = 1
+	alsoMine := db.NewRoom(1000)
+	alsoMine.ID = 2
+	theirs := db.NewRoom(1001)
+	theirs.ID = 3
+	open := db.NewRoom(1001)
+	open.ID = 4
+	open.Perms.Carry = db.PermWorld
+	// permissions that were never loaded don't let anyone in
+	unknown := db.NewRoom(1001)
+	unknown.ID = 5
+	unknown.Perms = nil
+
+	taken := takenExits(map[int][]string{1: {"north"}, 2: {"west"}})
+
+	tests := []struct {
+		name        string
+		here, there *db.Object
+		dir         string
+		want        string
+	}{
+		{name: "both mine", here: mine, there: alsoMine, dir: "south", want: ""},
+		{name: "same room", here: mine, there: mine, dir: "south", want: "you're already here."},
+		{name: "into someone else's room", here: mine, there: theirs, dir: "south",
+			want: fmt.Sprintf("%s isn't yours to put a door in.", theirs.String())},
+		{name: "out of someone else's room", here: theirs, there: mine, dir: "south",
+			want: fmt.Sprintf("%s isn't yours to put a door in.", theirs.String())},
+		{name: "into a room anyone may use", here: mine, there: open, dir: "south", want: ""},
+		{name: "into a room with unknown permissions", here: mine, there: unknown, dir: "south",
+			want: fmt.Sprintf("%s isn't yours to put a door in.", unknown.String())},
+		{name: "taken here", here: mine, there: alsoMine, dir: "north",
+			want: "there's already a way north from here."},
+		{name: "taken on the way back", here: mine, there: alsoMine, dir: "east",
+			want: fmt.Sprintf("there's already a way west from %s.", alsoMine.String())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := linkRefusal(1000, *tt.here, *tt.there, witch.NormalizeDirection(tt.dir), taken)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
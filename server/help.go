@@ -0,0 +1,157 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vilmibm/hermeticum/proto"
+	"github.com/vilmibm/hermeticum/server/db"
+	"github.com/vilmibm/hermeticum/server/witch"
+)
+
+func init() {
+	registerVerb(&verb{
+		Name:    "help",
+		Aliases: []string{"?"},
+		Usage:   "[verb|here]",
+		Help:    "list builtin verbs, explain one verb or list what the things around you respond to.",
+		Handler: (*gameWorldServer).handleHelp,
+	})
+}
+
+// scriptHooks are handled by scripts but can't be used as verbs by players.
+var scriptHooks = map[string]bool{
+	"arrives": true,
+	"departs": true,
+}
+
+// nearbyHandler is a handler registered by the script of something in earshot.
+type nearbyHandler struct {
+	obj *db.Object
+	witch.VerbHelp
+}
+
+func (nh nearbyHandler) String() string {
+	pattern := nh.Pattern
+	if pattern == ".*" {
+		pattern = ""
+	}
+
+	var line string
+	switch nh.Verb {
+	case "say":
+		line = "saying anything"
+		if pattern != "" {
+			line = fmt.Sprintf("saying something like /%s/", pattern)
+		}
+	default:
+		line = strings.TrimSpace(fmt.Sprintf("/%s %s", nh.Verb, pattern))
+	}
+
+	if nh.Help != "" {
+		line += " - " + nh.Help
+	}
+
+	return line
+}
+
+// nearbyHandlers asks the script of everything in avatar's earshot what
+// it responds to. Things avatar can't execute are left out, as are
+// scriptHooks.
+func (s *gameWorldServer) nearbyHandlers(avatar db.Object) ([]nearbyHandler, error) {
+	eshot, err := avatar.Earshot(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []nearbyHandler{}
+	for _, o := range eshot {
+		if o.Perms.Exec == db.PermOwner && avatar.OwnerID != o.OwnerID {
+			continue
+		}
+		sc, err := s.scriptFor(*o)
+		if err != nil {
+			return nil, err
+		}
+		for _, vh := range sc.Verbs(*o) {
+			if scriptHooks[vh.Verb] {
+				continue
+			}
+			out = append(out, nearbyHandler{obj: o, VerbHelp: vh})
+		}
+	}
+
+	return out, nil
+}
+
+func (s *gameWorldServer) handleHelp(avatar db.Object, cmd *proto.Command) error {
+	priv := s.privilegeOf(avatar)
+
+	topic := strings.TrimPrefix(strings.TrimSpace(cmd.Rest), "/")
+
+	switch topic {
+	case "":
+		msg := "builtin verbs:"
+		for _, v := range builtinVerbs(priv) {
+			msg += fmt.Sprintf("\n  %-22s %s", strings.TrimSpace("/"+v.Name+" "+v.Usage), v.Help)
+		}
+		msg += "\nanything else after a slash is passed on to the things around you." +
+			"\nuse /help here to see what they respond to and /help <verb> to learn more about a verb."
+		s.printTo(avatar, msg)
+		return nil
+	case "here":
+		nearby, err := s.nearbyHandlers(avatar)
+		if err != nil {
+			return err
+		}
+		if len(nearby) == 0 {
+			s.printTo(avatar, "nothing around you responds to anything in particular.")
+			return nil
+		}
+		msg := "things around you respond to:"
+		var last *db.Object
+		for _, nh := range nearby {
+			if nh.obj != last {
+				msg += fmt.Sprintf("\n  %s:", nh.obj.String())
+				last = nh.obj
+			}
+			msg += "\n    " + nh.String()
+		}
+		s.printTo(avatar, msg)
+		return nil
+	}
+
+	msg := ""
+	if v, ok := verbs[topic]; ok && v.Privilege <= priv {
+		msg = strings.TrimSpace("/" + v.Name + " " + v.Usage)
+		if len(v.Aliases) > 0 {
+			msg += "\nalso: /" + strings.Join(v.Aliases, ", /")
+		}
+		msg += "\n" + v.Help
+		if v.RateLimit > 0 {
+			msg += fmt.Sprintf("\nyou can do this once every %s.", v.RateLimit)
+		}
+		if v.Privilege == privStaff {
+			msg += "\nonly staff can do this."
+		}
+	}
+
+	nearby, err := s.nearbyHandlers(avatar)
+	if err != nil {
+		return err
+	}
+
+	for _, nh := range nearby {
+		if nh.Verb == topic {
+			msg += fmt.Sprintf("\n%s: %s", nh.obj.String(), nh.String())
+		}
+	}
+
+	if msg == "" {
+		msg = fmt.Sprintf("neither hermeticum nor anything around you knows how to /%s.", topic)
+	}
+
+	s.printTo(avatar, strings.TrimSpace(msg))
+
+	return nil
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vilmibm/hermeticum/proto"
+	"github.com/vilmibm/hermeticum/server/db"
+)
+
+func init() {
+	registerVerb(&verb{
+		Name:    "whisper",
+		Aliases: []string{"w", "tell"},
+		Usage:   "<user> <message>",
+		Help:    "say something only user hears, wherever they are. if they aren't connected it goes in their mailbox.",
+		Handler: (*gameWorldServer).handleWhisper,
+	})
+	registerVerb(&verb{
+		Name:      "mail",
+		Usage:     "[read [number]|send <user> <message>|delete <number>]",
+		Help:      "read, send and delete messages left for users who weren't around.",
+		RateLimit: time.Second,
+		Handler:   (*gameWorldServer).handleMail,
+	})
+}
+
+// sessionFor finds the session of the user called username.
+func (s *gameWorldServer) sessionFor(username string) (*userIO, bool) {
+	for _, uio := range s.connected() {
+		if strings.EqualFold(uio.username, username) {
+			return uio, true
+		}
+	}
+
+	return nil, false
+}
+
+// mailTo leaves body in username's mailbox, telling them about it if they are
+// connected. It returns false (having told avatar why) if there is no such
+// user.
+func (s *gameWorldServer) mailTo(avatar db.Object, username, body string) (bool, error) {
+	uid, ok := lookupUID(username)
+	if !ok {
+		s.printTo(avatar, fmt.Sprintf("there is no one called %s.", username))
+		return false, nil
+	}
+
+	if err := s.db.SendMail(s.usernameOf(avatar), uid, body); err != nil {
+		return false, err
+	}
+
+	if uio, ok := s.sessionFor(username); ok {
+		s.printTo(uio.avatar, fmt.Sprintf(
+			"you have new mail from %s. use /mail read to see it.", s.usernameOf(avatar)))
+	}
+
+	return true, nil
+}
+
+func (s *gameWorldServer) usernameOf(avatar db.Object) string {
+	if uio, ok := s.session(uint32(avatar.OwnerID)); ok {
+		return uio.username
+	}
+
+	return avatar.GetData("name")
+}
+
+// deliverMail whispers everything in uid's mailbox that hasn't been read yet.
+func (s *gameWorldServer) deliverMail(uid uint32) {
+	uio, ok := s.session(uid)
+	if !ok {
+		return
+	}
+
+	mail, err := s.db.Mailbox(uid, true)
+	if err != nil {
+		log.Printf("failed to check mail for %d: %s", uid, err.Error())
+		return
+	}
+
+	for _, m := range mail {
+		sender := m.Sender
+		text := fmt.Sprintf("%s (mailed %s ago)", m.Body, idleString(time.Since(m.Sent)))
+		uio.send(&proto.WorldEvent{
+			Type:   proto.WorldEvent_WHISPER,
+			Source: &sender,
+			Text:   &text,
+		})
+		if err = s.db.MarkMailRead(uid, m.ID); err != nil {
+			log.Printf("failed to mark mail %d read: %s", m.ID, err.Error())
+		}
+	}
+}
+
+func (s *gameWorldServer) handleWhisper(avatar db.Object, cmd *proto.Command) error {
+	username, msg, _ := strings.Cut(strings.TrimSpace(cmd.Rest), " ")
+	msg = strings.TrimSpace(msg)
+	if username == "" || msg == "" {
+		s.printTo(avatar, "usage: /whisper <user> <message>")
+		return nil
+	}
+
+	uio, ok := s.sessionFor(username)
+	if !ok {
+		sent, err := s.mailTo(avatar, username, msg)
+		if sent {
+			s.printTo(avatar, fmt.Sprintf("%s isn't connected so your whisper is waiting in their mailbox.", username))
+		}
+		return err
+	}
+
+	sender := s.usernameOf(avatar)
+	uio.send(&proto.WorldEvent{
+		Type:   proto.WorldEvent_WHISPER,
+		Source: &sender,
+		Text:   &msg,
+	})
+
+	s.printTo(avatar, fmt.Sprintf("you whisper to %s: %s", uio.username, msg))
+
+	return nil
+}
+
+func (s *gameWorldServer) handleMail(avatar db.Object, cmd *proto.Command) error {
+	uid := uint32(avatar.OwnerID)
+	action, rest, _ := strings.Cut(strings.TrimSpace(cmd.Rest), " ")
+	rest = strings.TrimSpace(rest)
+
+	switch action {
+	case "", "read":
+		mail, err := s.db.Mailbox(uid, false)
+		if err != nil {
+			return err
+		}
+
+		if rest == "" {
+			if len(mail) == 0 {
+				s.printTo(avatar, "your mailbox is empty.")
+				return nil
+			}
+			msg := "your mailbox:"
+			for _, m := range mail {
+				status := ""
+				if !m.Read {
+					status = " (new)"
+				}
+				first, _, cut := strings.Cut(m.Body, "\n")
+				body := []rune(first)
+				if cut || len(body) > 50 {
+					first = strings.TrimSpace(string(body[:min(len(body), 50)])) + "..."
+				}
+				msg += fmt.Sprintf("\n  %d. from %s, %s ago%s: %s",
+					m.ID, m.Sender, idleString(time.Since(m.Sent)), status, first)
+			}
+			msg += "\nuse /mail read <number> to read one and /mail delete <number> to throw one away."
+			s.printTo(avatar, msg)
+			return nil
+		}
+
+		id, err := strconv.Atoi(rest)
+		if err != nil {
+			s.printTo(avatar, "usage: /mail read <number>")
+			return nil
+		}
+		for _, m := range mail {
+			if m.ID == id {
+				s.printTo(avatar, fmt.Sprintf("from %s, %s ago:\n%s",
+					m.Sender, idleString(time.Since(m.Sent)), m.Body))
+				return s.db.MarkMailRead(uid, id)
+			}
+		}
+		s.printTo(avatar, fmt.Sprintf("there is no message %d in your mailbox.", id))
+	case "send":
+		username, body, _ := strings.Cut(rest, " ")
+		body = strings.TrimSpace(body)
+		if username == "" || body == "" {
+			s.printTo(avatar, "usage: /mail send <user> <message>")
+			return nil
+		}
+		sent, err := s.mailTo(avatar, username, body)
+		if sent {
+			s.printTo(avatar, fmt.Sprintf("your message is in %s's mailbox.", username))
+		}
+		return err
+	case "delete":
+		id, err := strconv.Atoi(rest)
+		if err != nil {
+			s.printTo(avatar, "usage: /mail delete <number>")
+			return nil
+		}
+		deleted, err := s.db.DeleteMail(uid, id)
+		if err != nil {
+			return err
+		}
+		if deleted {
+			s.printTo(avatar, fmt.Sprintf("threw away message %d.", id))
+		} else {
+			s.printTo(avatar, fmt.Sprintf("there is no message %d in your mailbox.", id))
+		}
+	default:
+		s.printTo(avatar, "usage: /mail [read [number]|send <user> <message>|delete <number>]")
+	}
+
+	return nil
+}
@@ -8,9 +8,11 @@ import (
 	"net"
 	"os"
 	"os/user"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/vilmibm/hermeticum/proto"
@@ -47,7 +49,20 @@ and the edit handler does the update and unlock
 so to start, let's support 'dig'.
 */
 
+// ServeOpts is read from the server config file (see LoadServeOpts) and then
+// overridden by command line flags.
 type ServeOpts struct {
+	// Address is the path of the unix socket to listen on.
+	Address string `toml:"address"`
+	// DSN is a postgres connection string. When empty the PG* environment
+	// variables are used.
+	DSN string `toml:"dsn"`
+	// StaffGroup is a unix group whose members, along with root, may use
+	// staff only verbs.
+	StaffGroup string `toml:"staff_group"`
+	// ObjectQuota is how many objects a user (or their scripts) may own. Zero
+	// means no limit.
+	ObjectQuota int `toml:"object_quota"`
 }
 
 type ServerAuthCredentials struct {
@@ -111,9 +126,12 @@ func (*ServerAuthCredentials) ServerHandshake(conn net.Conn) (net.Conn, credenti
 	return conn, pai, nil
 }
 
-const sockAddr = "/tmp/hermeticum.sock"
-
 func Serve(opts ServeOpts) error {
+	sockAddr, err := socketPath(opts.Address)
+	if err != nil {
+		return err
+	}
+
 	os.Remove(sockAddr)
 
 	l, err := net.Listen("unix", sockAddr)
@@ -125,7 +143,7 @@ func Serve(opts ServeOpts) error {
 	os.Chmod(sockAddr, 0777) // frisson
 
 	gs := grpc.NewServer(grpc.Creds(&ServerAuthCredentials{}))
-	s, err := newServer()
+	s, err := newServer(opts)
 	if err != nil {
 		return err
 	}
@@ -140,6 +158,7 @@ func Serve(opts ServeOpts) error {
 type gameWorldServer struct {
 	proto.UnimplementedGameWorldServer
 
+	opts         ServeOpts
 	db           *db.DB
 	sessions     map[uint32]*userIO
 	sessionMutex sync.Mutex
@@ -147,20 +166,22 @@ type gameWorldServer struct {
 	scriptsMutex sync.RWMutex
 }
 
-func newServer() (*gameWorldServer, error) {
-	db, err := db.NewDB()
+func newServer(opts ServeOpts) (*gameWorldServer, error) {
+	db, err := db.NewDB(opts.DSN)
 	if err != nil {
 		return nil, err
 	}
 	if err = db.Ensure(); err != nil {
 		return nil, fmt.Errorf("failed to ensure default entities: %w", err)
 	}
+	db.SetObjectQuota(opts.ObjectQuota)
 
 	if err = db.GhostBust(); err != nil {
 		return nil, fmt.Errorf("could not clear sessions: %w", err)
 	}
 
 	s := &gameWorldServer{
+		opts:         opts,
 		sessions:     make(map[uint32]*userIO),
 		db:           db,
 		scripts:      make(map[int]*witch.ScriptContext),
@@ -170,36 +191,68 @@ func newServer() (*gameWorldServer, error) {
 	return s, nil
 }
 
-func (s *gameWorldServer) verbHandler(verb, rest string, sender, target db.Object) error {
-	log.Printf("VH %s %s %d %d", verb, rest, sender.ID, target.ID)
-
-	// TODO check lock
-
-	if target.Perms.Exec == db.PermOwner && sender.ID != target.OwnerID {
-		return nil
-	}
-
+// scriptFor returns the ScriptContext that runs target's script, starting one
+// if needed.
+func (s *gameWorldServer) scriptFor(target db.Object) (*witch.ScriptContext, error) {
 	s.scriptsMutex.RLock()
 	sc, ok := s.scripts[target.ID]
 	s.scriptsMutex.RUnlock()
-	var err error
+	if ok && sc != nil {
+		return sc, nil
+	}
 
 	clientSend := func(uid uint32, ev *proto.WorldEvent) {
-		if uio, ok := s.sessions[uid]; ok {
-			uio.outbound <- ev
+		if uio, ok := s.session(uid); ok {
+			uio.send(ev)
 		} else {
 			// TODO log this
 		}
 	}
 
-	if !ok || sc == nil {
-		if sc, err = witch.NewScriptContext(s.db, clientSend); err != nil {
-			return err
-		}
+	sc, err := witch.NewScriptContext(s.db, clientSend, s.moveInto, s.teleport, s.create, s.send, s.allowedMove)
+	if err != nil {
+		return nil, err
+	}
+
+	s.scriptsMutex.Lock()
+	s.scripts[target.ID] = sc
+	s.scriptsMutex.Unlock()
+
+	return sc, nil
+}
+
+// send delivers a verb from one object straight to another's script (see
+// WITCH's send). The verb is queued behind whatever the target already has
+// to do rather than waited for, since objects may well send to each other,
+// or to themselves.
+func (s *gameWorldServer) send(vc witch.VerbContext) error {
+	// a target whose permissions weren't loaded only takes its owner's verbs
+	if vc.Sender.OwnerID != vc.Target.OwnerID && (vc.Target.Perms == nil || vc.Target.Perms.Exec == db.PermOwner) {
+		return fmt.Errorf("%s only takes verbs from its owner's things", vc.Target.String())
+	}
+
+	sc, err := s.scriptFor(vc.Target)
+	if err != nil {
+		return err
+	}
+
+	sc.Handle(vc)
+
+	return nil
+}
 
-		s.scriptsMutex.Lock()
-		s.scripts[target.ID] = sc
-		s.scriptsMutex.Unlock()
+func (s *gameWorldServer) verbHandler(verb, rest string, sender, target db.Object) error {
+	log.Printf("VH %s %s %d %d", verb, rest, sender.ID, target.ID)
+
+	// TODO check lock
+
+	if target.Perms.Exec == db.PermOwner && sender.OwnerID != target.OwnerID {
+		return nil
+	}
+
+	sc, err := s.scriptFor(target)
+	if err != nil {
+		return err
 	}
 
 	vc := witch.VerbContext{
@@ -207,6 +260,9 @@ func (s *gameWorldServer) verbHandler(verb, rest string, sender, target db.Objec
 		Rest:   rest,
 		Sender: sender,
 		Target: target,
+		// hooks happen to the sender and shouts come from far away; anything
+		// else was done by the sender right here
+		Invoked: !scriptHooks[verb] && verb != "shout",
 	}
 
 	sc.Handle(vc)
@@ -215,10 +271,91 @@ func (s *gameWorldServer) verbHandler(verb, rest string, sender, target db.Objec
 }
 
 type userIO struct {
+	avatar   db.Object
+	username string
 	inbound  chan *proto.Command
 	outbound chan *proto.WorldEvent
 	errs     chan error
 	done     chan bool
+	// gone is closed once the session loop has stopped draining outbound.
+	gone chan struct{}
+	// privilege is what builtin verbs the user can use.
+	privilege privilege
+	// lastUsed is when each rate limited verb was last used. It is only
+	// touched by the session loop.
+	lastUsed map[string]time.Time
+	// lastCommand is when the user last sent anything, in unix nanoseconds.
+	lastCommand atomic.Int64
+	// hidden keeps the user's room out of /who and /where.
+	hidden atomic.Bool
+	// pending is set when a command matched several objects; see resolveOne.
+	pending      *pendingChoice
+	pendingMutex sync.Mutex
+}
+
+// outboundQueue is how many events can wait for a session loop to send them
+// before anything else sending to the session has to wait too.
+const outboundQueue = 64
+
+// send queues ev for uio's client. Events from any one caller arrive in the
+// order they were sent. Once the session has ended ev is dropped.
+func (uio *userIO) send(ev *proto.WorldEvent) {
+	select {
+	case uio.outbound <- ev:
+	case <-uio.gone:
+	}
+}
+
+// session is the session for uid, if uid is connected. Sessions come and go
+// as users connect, so the map is only ever read through here or connected.
+func (s *gameWorldServer) session(uid uint32) (*userIO, bool) {
+	s.sessionMutex.Lock()
+	defer s.sessionMutex.Unlock()
+
+	uio, ok := s.sessions[uid]
+	return uio, ok
+}
+
+func (uio *userIO) idle() time.Duration {
+	return time.Since(time.Unix(0, uio.lastCommand.Load()))
+}
+
+// pendingChoice remembers a command whose target was ambiguous along with the
+// numbered options the player was shown.
+type pendingChoice struct {
+	cmd     *proto.Command
+	options []*db.Object
+}
+
+// choose checks whether cmd is a reply to a pendingChoice (just a number,
+// said or given as a verb) and if so returns the original command aimed at
+// the chosen object. Anything else abandons the choice.
+func (uio *userIO) choose(cmd *proto.Command) *proto.Command {
+	uio.pendingMutex.Lock()
+	pc := uio.pending
+	uio.pending = nil
+	uio.pendingMutex.Unlock()
+
+	if pc == nil {
+		return cmd
+	}
+
+	reply := cmd.Verb
+	if cmd.Verb == "say" {
+		reply = cmd.Rest
+	} else if cmd.Rest != "" {
+		return cmd
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(reply))
+	if err != nil || n < 1 || n > len(pc.options) {
+		return cmd
+	}
+
+	return &proto.Command{
+		Verb: pc.cmd.Verb,
+		Rest: strconv.Itoa(pc.options[n-1].ID),
+	}
 }
 
 func (s *gameWorldServer) ClientInput(stream proto.GameWorld_ClientInputServer) error {
@@ -232,9 +369,8 @@ func (s *gameWorldServer) ClientInput(stream proto.GameWorld_ClientInputServer)
 		return errors.New("failed to cast PeerAuthInfo")
 	}
 	uid := pai.ucred.Uid
-	// gid := pai.ucred.Gid TODO staff powers
 
-	if _, ok := s.sessions[uid]; ok {
+	if _, ok := s.session(uid); ok {
 		return fmt.Errorf("existing session for %d", uid)
 	}
 
@@ -251,32 +387,42 @@ func (s *gameWorldServer) ClientInput(stream proto.GameWorld_ClientInputServer)
 	log.Printf("uid %d connected", uid)
 
 	uio := &userIO{
-		inbound:  make(chan *proto.Command),
-		outbound: make(chan *proto.WorldEvent),
-		errs:     make(chan error, 1),
-		done:     make(chan bool, 1),
-	}
-
-	rootu, err := user.Lookup("root")
+		avatar:    *avatar,
+		username:  u.Username,
+		inbound:   make(chan *proto.Command),
+		outbound:  make(chan *proto.WorldEvent, outboundQueue),
+		errs:      make(chan error, 1),
+		done:      make(chan bool, 1),
+		gone:      make(chan struct{}),
+		privilege: privilegeFor(u, s.opts.StaffGroup),
+		lastUsed:  map[string]time.Time{},
+	}
+	uio.lastCommand.Store(time.Now().UnixNano())
+
+	hidden, err := s.db.Hidden(*avatar)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to look up privacy for %d: %w", uid, err)
 	}
-	ruid, err := strconv.Atoi(rootu.Uid)
-	if err != nil {
-		return err
-	}
-
-	rootuid := uint32(ruid)
+	uio.hidden.Store(hidden)
 
 	s.sessionMutex.Lock()
+	if _, ok := s.sessions[uid]; ok {
+		// connected again while this session was being set up
+		s.sessionMutex.Unlock()
+		return fmt.Errorf("existing session for %d", uid)
+	}
 	s.sessions[uid] = uio
 	s.sessionMutex.Unlock()
 
+	s.sendPresence(u.Username, true)
+
 	defer func() {
 		log.Printf("ending session for %d", uid)
+		close(uio.gone)
 		s.sessionMutex.Lock()
 		delete(s.sessions, uid)
 		s.sessionMutex.Unlock()
+		s.sendPresence(u.Username, false)
 		affected, err := avatar.Earshot(s.db)
 		if err != nil {
 			log.Printf("error trying to inform others about a derez: %s", err.Error())
@@ -284,21 +430,21 @@ func (s *gameWorldServer) ClientInput(stream proto.GameWorld_ClientInputServer)
 		}
 		s.db.Derez(uid)
 
+		aname := avatar.GetData("name")
+		if aname == "" {
+			aname = "amorphous entity"
+		}
+		msg := "slowly fades out of existence"
+
+		s.announce(affected, *avatar, &proto.WorldEvent{
+			Type:   proto.WorldEvent_EMOTE,
+			Source: &aname,
+			Text:   &msg,
+		}, "departs")
+
 		for _, obj := range affected {
 			if obj.Avatar {
-				aio, ok := s.sessions[uint32(obj.OwnerID)]
-				if ok {
-					aname, ok := avatar.Data["name"]
-					if !ok {
-						aname = "amorphous entity"
-					}
-					msg := "slowly fades out of existence"
-					aio.outbound <- &proto.WorldEvent{
-						Type:   proto.WorldEvent_EMOTE,
-						Source: &aname,
-						Text:   &msg,
-					}
-				}
+				s.sendRoom(*obj)
 			}
 		}
 	}()
@@ -314,39 +460,29 @@ func (s *gameWorldServer) ClientInput(stream proto.GameWorld_ClientInputServer)
 		}
 	}()
 
-	foyer, err := s.db.GetObject(rootuid, "foyer")
+	room, err := s.db.LastRoom(*avatar)
 	if err != nil {
-		return fmt.Errorf("failed to find foyer: %w", err)
+		log.Printf("no last room or home for %d, using foyer: %s", avatar.ID, err.Error())
+		if room, err = s.db.Foyer(); err != nil {
+			return fmt.Errorf("failed to find foyer: %w", err)
+		}
 	}
 
-	if err = avatar.MoveInto(s.db, *foyer); err != nil {
-		return fmt.Errorf("failed to move %d into %d: %w", avatar.ID, foyer.ID, err)
+	if err = s.moveInto(*avatar, *room); err != nil {
+		return fmt.Errorf("failed to move %d into %d: %w", avatar.ID, room.ID, err)
 	}
 
+	go s.deliverMail(uid)
+
 	for {
 		var handler func(db.Object, *proto.Command) error
 		var cmd *proto.Command
 		select {
 		case cmd = <-uio.inbound:
 			log.Printf("cmd %s %s from uid %d", cmd.Verb, cmd.Rest, uid)
-			switch cmd.Verb {
-			case "look":
-				handler = s.handleLook
-			case "quit":
-				uio.done <- true
-			case "dig":
-				handler = s.handleDig
-			case "inv":
-				handler = s.handleInv
-			case "get":
-				handler = s.handleGet
-			case "drop":
-				handler = s.handleDrop
-			case "create":
-				handler = s.handleCreate
-			default:
-				handler = s.handleCmd
-			}
+			uio.lastCommand.Store(time.Now().UnixNano())
+			cmd = uio.choose(cmd)
+			handler = s.dispatch(uio, cmd)
 		case ev := <-uio.outbound:
 			if err := stream.Send(ev); err != nil {
 				uio.errs <- err
@@ -373,10 +509,185 @@ func (s *gameWorldServer) ClientInput(stream proto.GameWorld_ClientInputServer)
 // TODO handleUpdateObj
 
 func (s *gameWorldServer) printTo(avatar db.Object, msg string) {
-	s.sessions[uint32(avatar.OwnerID)].outbound <- &proto.WorldEvent{
+	uio, ok := s.session(uint32(avatar.OwnerID))
+	if !ok {
+		return
+	}
+
+	uio.send(&proto.WorldEvent{
 		Type: proto.WorldEvent_PRINT,
 		Text: &msg,
+	})
+}
+
+// sendRoom tells avatar's client what room it is in and what it can see there.
+func (s *gameWorldServer) sendRoom(avatar db.Object) {
+	uio, ok := s.session(uint32(avatar.OwnerID))
+	if !ok {
+		return
+	}
+
+	room, err := avatar.Container(s.db)
+	if err != nil {
+		log.Printf("failed to find room for %d: %s", avatar.ID, err.Error())
+		return
+	}
+
+	os, err := room.Contents(s.db)
+	if err != nil {
+		log.Printf("failed to get contents of %d: %s", room.ID, err.Error())
+		return
 	}
+
+	rs := &proto.RoomState{
+		Id:    int32(room.ID),
+		Name:  room.GetData("name"),
+		Verbs: verbNames(uio.privilege),
+	}
+	for _, o := range os {
+		rs.Contents = append(rs.Contents, &proto.ObjectSummary{
+			Id:   int32(o.ID),
+			Name: o.GetData("name"),
+		})
+	}
+
+	uio.send(&proto.WorldEvent{
+		Type: proto.WorldEvent_ROOM,
+		Room: rs,
+	})
+}
+
+// moveInto moves obj into container, tells everything near it before and
+// after the move that it left and arrived, and refreshes the room state of
+// any avatars among them.
+func (s *gameWorldServer) moveInto(obj, container db.Object) error {
+	return s.relocate(obj, container, "", "")
+}
+
+// teleport is moveInto for moves that don't happen by walking.
+func (s *gameWorldServer) teleport(obj, container db.Object) error {
+	return s.relocate(obj, container, "vanishes.", "appears out of nowhere.")
+}
+
+// relocate does the work of moveInto. leaveText and enterText replace the
+// usual "leaves." and "arrives." when set.
+func (s *gameWorldServer) relocate(obj, container db.Object, leaveText, enterText string) error {
+	// obj has no container yet when it has just been created or rezzed
+	from, _ := obj.Container(s.db)
+
+	before, err := obj.Earshot(s.db)
+	if err != nil {
+		return err
+	}
+
+	if err = obj.MoveInto(s.db, container); err != nil {
+		return err
+	}
+
+	after, err := obj.Earshot(s.db)
+	if err != nil {
+		return err
+	}
+
+	name := obj.GetData("name")
+	leave := &proto.WorldEvent{Type: proto.WorldEvent_LEAVE, Source: &name}
+	if leaveText != "" {
+		leave.Text = &leaveText
+	} else if container.Avatar {
+		text := fmt.Sprintf("is picked up by %s.", container.GetData("name"))
+		leave.Text = &text
+	}
+	enter := &proto.WorldEvent{Type: proto.WorldEvent_ENTER, Source: &name}
+	// whoever is doing the picking up or putting down hears about it from the
+	// verb instead
+	enterSkip := []int{}
+	if from != nil {
+		enterSkip = append(enterSkip, from.ID)
+		if from.Avatar {
+			text := fmt.Sprintf("is put down by %s.", from.GetData("name"))
+			enter.Text = &text
+		}
+	}
+	if enterText != "" {
+		enter.Text = &enterText
+	}
+
+	s.announce(before, obj, leave, "departs", container.ID)
+	s.announce(after, obj, enter, "arrives", enterSkip...)
+
+	seen := map[int]bool{}
+	for _, o := range append(before, after...) {
+		if o.Avatar && !seen[o.ID] {
+			seen[o.ID] = true
+			s.sendRoom(*o)
+		}
+	}
+
+	return nil
+}
+
+// announce tells the avatars among heard about ev and the scripts of
+// everything in heard about hook (arrives or departs), with obj as the sender.
+// obj itself and anything in skip are left out.
+func (s *gameWorldServer) announce(heard []*db.Object, obj db.Object, ev *proto.WorldEvent, hook string, skip ...int) {
+	for _, o := range heard {
+		if o.ID == obj.ID || slices.Contains(skip, o.ID) {
+			continue
+		}
+		if o.Avatar {
+			if uio, ok := s.session(uint32(o.OwnerID)); ok {
+				uio.send(ev)
+			}
+		}
+		if err := s.verbHandler(hook, "", obj, *o); err != nil {
+			log.Printf("error handling verb %s for object %d: %s", hook, o.ID, err)
+		}
+	}
+}
+
+// resolveOne finds the single object among candidates that term refers to
+// (see db.Match). If there isn't exactly one it explains why to avatar and
+// returns nil. When there are several it lists them by number and remembers
+// cmd so that a reply of just a number can finish it.
+func (s *gameWorldServer) resolveOne(avatar db.Object, cmd *proto.Command, candidates []*db.Object, term, notFound string) (*db.Object, error) {
+	os, err := s.db.Match(avatar, candidates, term)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(os) == 0 {
+		s.printTo(avatar, notFound)
+		return nil, nil
+	}
+
+	if len(os) == 1 {
+		return os[0], nil
+	}
+
+	msg := "could you be more specific? that might be a few things:\n"
+	for ix, o := range os {
+		msg += fmt.Sprintf("%d. %s\n", ix+1, o.String())
+	}
+	msg += fmt.Sprintf("reply with a number to choose, or next time say something like /%s 2.%s",
+		cmd.Verb, term)
+
+	if uio, ok := s.session(uint32(avatar.OwnerID)); ok {
+		uio.pendingMutex.Lock()
+		uio.pending = &pendingChoice{cmd: cmd, options: os}
+		uio.pendingMutex.Unlock()
+	}
+
+	s.printTo(avatar, msg)
+
+	return nil, nil
+}
+
+func (s *gameWorldServer) handleQuit(avatar db.Object, cmd *proto.Command) error {
+	if uio, ok := s.session(uint32(avatar.OwnerID)); ok {
+		uio.done <- true
+	}
+
+	return nil
 }
 
 func (s *gameWorldServer) handleDrop(avatar db.Object, cmd *proto.Command) error {
@@ -395,24 +706,18 @@ func (s *gameWorldServer) handleDrop(avatar db.Object, cmd *proto.Command) error
 		return nil
 	}
 
-	os := db.Filter(cts, cmd.Rest)
-
-	if len(os) == 0 {
-		s.printTo(avatar, fmt.Sprintf("You see nothing in your pockets called '%s'", cmd.Rest))
-		return nil
+	rest, ok, err := s.allowed(avatar, "drop", cmd.Rest, s.likelySubject(avatar, cts, cmd.Rest))
+	if !ok {
+		return err
 	}
+	cmd = &proto.Command{Verb: cmd.Verb, Rest: rest}
 
-	if len(os) > 1 {
-		// TODO might be nice to make this a helper (fuzzySelect or something)
-		msg := "could you be more specific? that might be a few things:\n"
-		for _, o := range os {
-			msg += fmt.Sprintf("- %s\n", o.String())
-		}
-		return nil
+	target, err := s.resolveOne(avatar, cmd, cts, cmd.Rest,
+		fmt.Sprintf("You see nothing in your pockets called '%s'", cmd.Rest))
+	if target == nil {
+		return err
 	}
 
-	target := os[0]
-
 	room, err := avatar.Container(s.db)
 	if err != nil {
 		return err
@@ -420,7 +725,7 @@ func (s *gameWorldServer) handleDrop(avatar db.Object, cmd *proto.Command) error
 
 	// TODO if target is a room it now exists in the world as contained...FYI...
 
-	err = target.MoveInto(s.db, *room)
+	err = s.moveInto(*target, *room)
 	if err != nil {
 		return err
 	}
@@ -447,23 +752,18 @@ func (s *gameWorldServer) handleGet(avatar db.Object, cmd *proto.Command) error
 		return err
 	}
 
-	os := db.Filter(eshot, cmd.Rest)
-
-	if len(os) == 0 {
-		s.printTo(avatar, fmt.Sprintf("You see nothing nearby called '%s'", cmd.Rest))
-		return nil
+	rest, ok, err := s.allowed(avatar, "get", cmd.Rest, s.likelySubject(avatar, eshot, cmd.Rest))
+	if !ok {
+		return err
 	}
+	cmd = &proto.Command{Verb: cmd.Verb, Rest: rest}
 
-	if len(os) > 1 {
-		msg := "could you be more specific? that might be a few things:\n"
-		for _, o := range os {
-			msg += fmt.Sprintf("- %s\n", o.String())
-		}
-		return nil
+	target, err := s.resolveOne(avatar, cmd, eshot, cmd.Rest,
+		fmt.Sprintf("You see nothing nearby called '%s'", cmd.Rest))
+	if target == nil {
+		return err
 	}
 
-	target := os[0]
-
 	if target.ID == avatar.ID {
 		s.printTo(avatar, "You find yourself unable to put yourself into your own pocket.")
 		return nil
@@ -474,17 +774,17 @@ func (s *gameWorldServer) handleGet(avatar db.Object, cmd *proto.Command) error
 		return nil
 	}
 
-	err = target.MoveInto(s.db, avatar)
+	err = s.moveInto(*target, avatar)
 	if err != nil {
 		return err
 	}
 
 	if target.ID == room.ID {
-		foyer, err := s.db.ObjectByOwnerName(0, "foyer")
+		foyer, err := s.db.Foyer()
 		if err != nil {
 			return err
 		}
-		return avatar.MoveInto(s.db, *foyer)
+		return s.moveInto(avatar, *foyer)
 	}
 
 	s.printTo(avatar, fmt.Sprintf(
@@ -495,8 +795,6 @@ func (s *gameWorldServer) handleGet(avatar db.Object, cmd *proto.Command) error
 }
 
 func (s *gameWorldServer) handleLook(avatar db.Object, cmd *proto.Command) error {
-	uid := uint32(avatar.OwnerID)
-
 	room, err := avatar.Container(s.db)
 	if err != nil {
 		return err
@@ -536,17 +834,14 @@ You can see:
 
 	msg = strings.TrimSpace(msg)
 
-	s.sessions[uid].outbound <- &proto.WorldEvent{
-		Type: proto.WorldEvent_PRINT,
-		Text: &msg,
-	}
+	s.printTo(avatar, msg)
+
+	s.sendRoom(avatar)
 
 	return s.handleCmd(avatar, cmd)
 }
 
 func (s *gameWorldServer) handleInv(avatar db.Object, cmd *proto.Command) error {
-	uid := uint32(avatar.OwnerID)
-
 	os, err := avatar.Contents(s.db)
 	if err != nil {
 		return err
@@ -562,10 +857,7 @@ func (s *gameWorldServer) handleInv(avatar db.Object, cmd *proto.Command) error
 		msg += "\n\tnothing."
 	}
 
-	s.sessions[uid].outbound <- &proto.WorldEvent{
-		Type: proto.WorldEvent_PRINT,
-		Text: &msg,
-	}
+	s.printTo(avatar, msg)
 
 	for _, o := range os {
 		log.Printf("%s heard %s from %d", o.GetData("name"), "look", avatar.ID)
@@ -577,17 +869,58 @@ func (s *gameWorldServer) handleInv(avatar db.Object, cmd *proto.Command) error
 	return nil
 }
 
+// roomFor returns db.ErrQuota unless uid can own n more things without going
+// over their ObjectQuota. Save enforces the quota for each object; this is for
+// checking before making several things, so that nothing gets half made.
+func (s *gameWorldServer) roomFor(uid uint32, n int) error {
+	if s.opts.ObjectQuota <= 0 {
+		return nil
+	}
+
+	count, err := s.db.CountObjects(uid)
+	if err != nil {
+		return err
+	}
+	if count+n > s.opts.ObjectQuota {
+		return db.ErrQuota
+	}
+
+	return nil
+}
+
+// printQuota tells avatar they own too much to make anything else.
+func (s *gameWorldServer) printQuota(avatar db.Object) {
+	s.printTo(avatar, fmt.Sprintf(
+		"you already own %d things, which is as many as anyone may. get rid of something first.",
+		s.opts.ObjectQuota))
+}
+
+// create saves o as long as its owner hasn't used up their ObjectQuota.
+func (s *gameWorldServer) create(o *db.Object) error {
+	return o.Save(s.db)
+}
+
 func (s *gameWorldServer) handleCreate(avatar db.Object, cmd *proto.Command) error {
 	uid := uint32(avatar.OwnerID)
 
+	if _, ok, err := s.allowed(avatar, "create", cmd.Rest, nil); !ok {
+		return err
+	}
+
 	o := db.NewObject(uid)
 
-	err := o.Save(s.db)
+	err := s.create(o)
+	if errors.Is(err, db.ErrQuota) {
+		s.printQuota(avatar)
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	o.MoveInto(s.db, avatar)
+	if err = s.moveInto(*o, avatar); err != nil {
+		return err
+	}
 
 	s.printTo(avatar,
 		"the air right in front of you solidifies. you hear a small crack. something has fallen into your pocket. use /inv to see what you are holding.")
@@ -595,78 +928,119 @@ func (s *gameWorldServer) handleCreate(avatar db.Object, cmd *proto.Command) err
 	return nil
 }
 
-func (s *gameWorldServer) handleDig(avatar db.Object, cmd *proto.Command) error {
-	uid := uint32(avatar.OwnerID)
-	heading := cmd.Rest
-	if !witch.ValidDirection(heading) {
-		msg := fmt.Sprintf("sorry, %s is not a valid heading. valid headings are: %v", heading,
-			witch.Directions())
-
-		s.sessions[uid].outbound <- &proto.WorldEvent{
-			Type: proto.WorldEvent_PRINT,
-			Text: &msg,
-		}
+func (s *gameWorldServer) handleHome(avatar db.Object, cmd *proto.Command) error {
+	home, err := s.db.Home(avatar)
+	if err != nil {
+		s.printTo(avatar, "you have nowhere to call home. use /sethome to make one.")
+		return nil
 	}
-	dir := witch.NormalizeDirection(heading)
 
-	currentRoom, err := avatar.Container(s.db)
+	room, err := avatar.Container(s.db)
 	if err != nil {
 		return err
 	}
 
-	room := db.NewRoom(uid)
-	if err = room.Save(s.db); err != nil {
+	if room.ID == home.ID {
+		s.printTo(avatar, "you are already home.")
+		return nil
+	}
+
+	if err = s.moveInto(avatar, *home); err != nil {
 		return err
 	}
 
-	desc := "a simple wooden gate on a hinge"
-	name := "small gate"
-	log.Printf("%#v", dir)
-	if dir.IsVertical() {
-		desc = "a basic wooden ladder. it's a little rickety."
-		name = "ladder"
+	s.printTo(avatar, fmt.Sprintf(
+		"you close your eyes and think of home. you open them in %s.", home.String()))
+
+	return nil
+}
+
+func (s *gameWorldServer) handleSetHome(avatar db.Object, cmd *proto.Command) error {
+	room, err := avatar.Container(s.db)
+	if err != nil {
+		return err
 	}
 
-	door := db.NewObject(uid)
-	door.SetData("name", name)
-	door.SetData("description", desc)
-	door.AppendScript(fmt.Sprintf("goes(%s, %d)", heading, room.ID))
-	if err = door.Save(s.db); err != nil {
+	err = s.db.SetHome(avatar, *room)
+	if errors.Is(err, db.ErrNotPermitted) {
+		s.printTo(avatar, fmt.Sprintf(
+			"you can't make yourself at home in %s. it isn't yours.", room.String()))
+		return nil
+	}
+	if err != nil {
 		return err
 	}
 
-	revDoor := db.NewObject(uid)
-	revDoor.SetData("name", name)
-	revDoor.SetData("description", desc)
-	revDoor.AppendScript(fmt.Sprintf("goes(%s, %d)",
-		dir.Reverse().Human(), currentRoom.ID))
+	s.printTo(avatar, fmt.Sprintf(
+		"%s is now your home. use /home to return here.", room.String()))
+
+	return nil
+}
+
+func (s *gameWorldServer) handleDig(avatar db.Object, cmd *proto.Command) error {
+	uid := uint32(avatar.OwnerID)
+	heading := strings.TrimSpace(cmd.Rest)
+	if !witch.ValidDirection(heading) {
+		s.printTo(avatar, fmt.Sprintf("sorry, %s is not a valid heading. valid headings are: %v", heading,
+			witch.Directions()))
+		return nil
+	}
+	dir := witch.NormalizeDirection(heading)
 
-	if err = revDoor.Save(s.db); err != nil {
+	currentRoom, err := avatar.Container(s.db)
+	if err != nil {
 		return err
 	}
 
-	err = door.MoveInto(s.db, *currentRoom)
+	if why := digRefusal(*currentRoom, dir, s.exitTaken); why != "" {
+		s.printTo(avatar, why)
+		return nil
+	}
+
+	// a room and two doors
+	err = s.roomFor(uid, 3)
+	if errors.Is(err, db.ErrQuota) {
+		s.printQuota(avatar)
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	err = revDoor.MoveInto(s.db, *room)
+	room, e, err := s.dig(avatar, *currentRoom, dir)
+	// something else may have been made since roomFor looked
+	if errors.Is(err, db.ErrQuota) {
+		s.printQuota(avatar)
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	// TODO inform user about some things?
+	s.printTo(avatar, fmt.Sprintf("you dig %s. %s leads to %s.",
+		dir.Human(), e.Door.GetData("name"), room.GetData("name")))
+
 	return nil
 }
 
 func (s *gameWorldServer) handleCmd(avatar db.Object, cmd *proto.Command) error {
+	// going somewhere is up to whatever scripts provide the exits, but it's
+	// still a builtin action as far as before is concerned
+	if cmd.Verb == "go" {
+		rest, ok, err := s.allowed(avatar, "go", cmd.Rest, nil)
+		if !ok {
+			return err
+		}
+		cmd = &proto.Command{Verb: cmd.Verb, Rest: rest}
+	}
+
 	affected, err := avatar.Earshot(s.db)
 	if err != nil {
 		return err
 	}
 
 	for _, obj := range affected {
-		log.Printf("%s heard %s from %d", obj.Data["name"], cmd.Verb, avatar.ID)
+		log.Printf("%s heard %s from %d", obj.GetData("name"), cmd.Verb, avatar.ID)
 	}
 
 	for _, o := range affected {
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vilmibm/hermeticum/proto"
+	"github.com/vilmibm/hermeticum/server/db"
+)
+
+func TestUserIOSend(t *testing.T) {
+	uio := &userIO{
+		outbound: make(chan *proto.WorldEvent, outboundQueue),
+		gone:     make(chan struct{}),
+	}
+
+	for ix := 0; ix < outboundQueue; ix++ {
+		text := fmt.Sprint(ix)
+		uio.send(&proto.WorldEvent{Text: &text})
+	}
+	for ix := 0; ix < outboundQueue; ix++ {
+		if got := (<-uio.outbound).GetText(); got != fmt.Sprint(ix) {
+			t.Fatalf("event %d arrived as %s", ix, got)
+		}
+	}
+
+	for ix := 0; ix < outboundQueue; ix++ {
+		uio.send(&proto.WorldEvent{})
+	}
+	sent := make(chan bool)
+	go func() {
+		uio.send(&proto.WorldEvent{})
+		sent <- true
+	}()
+	close(uio.gone)
+
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("send to an ended session with a full queue never returned")
+	}
+}
+
+func TestSession(t *testing.T) {
+	s := &gameWorldServer{sessions: map[uint32]*userIO{}}
+
+	// users coming and going while handlers look sessions up; run with -race
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for ix := uint32(0); ix < 100; ix++ {
+			s.sessionMutex.Lock()
+			s.sessions[ix] = &userIO{}
+			s.sessionMutex.Unlock()
+		}
+	}()
+	for ix := uint32(0); ix < 100; ix++ {
+		s.session(ix)
+	}
+	wg.Wait()
+
+	if _, ok := s.session(100); ok {
+		t.Error("found a session for a user who never connected")
+	}
+	if _, ok := s.session(99); !ok {
+		t.Error("didn't find a session for a connected user")
+	}
+
+	// nobody to tell, which is fine
+	s.printTo(*db.NewAvatar(100, "nobody"), "hello?")
+}
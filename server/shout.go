@@ -0,0 +1,177 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vilmibm/hermeticum/proto"
+	"github.com/vilmibm/hermeticum/server/db"
+)
+
+const shoutCooldown = 30 * time.Second
+
+func init() {
+	registerVerb(&verb{
+		Name:      "shout",
+		Usage:     "<message>",
+		Help:      "say something everyone connected will hear, wherever they are.",
+		RateLimit: shoutCooldown,
+		Handler:   (*gameWorldServer).handleShout,
+	})
+	registerVerb(&verb{
+		Name:    "ignore",
+		Usage:   "[user]",
+		Help:    "stop hearing shouts from user. with no user, list who you are ignoring.",
+		Handler: (*gameWorldServer).handleIgnore,
+	})
+	registerVerb(&verb{
+		Name:    "unignore",
+		Usage:   "<user>",
+		Help:    "hear shouts from user again.",
+		Handler: (*gameWorldServer).handleUnignore,
+	})
+}
+
+// lookupUID finds the uid of the unix user called username.
+func lookupUID(username string) (uint32, bool) {
+	u, err := user.Lookup(username)
+	if err != nil {
+		return 0, false
+	}
+
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return 0, false
+	}
+
+	return uint32(uid), true
+}
+
+func (s *gameWorldServer) handleShout(avatar db.Object, cmd *proto.Command) error {
+	msg := strings.TrimSpace(cmd.Rest)
+	if msg == "" {
+		s.printTo(avatar, "usage: /shout <message>")
+		return nil
+	}
+
+	ignorers, err := s.db.Ignorers(uint32(avatar.OwnerID))
+	if err != nil {
+		return err
+	}
+	ignoring := map[uint32]bool{}
+	for _, uid := range ignorers {
+		ignoring[uid] = true
+	}
+
+	sender := s.usernameOf(avatar)
+	ev := &proto.WorldEvent{
+		Type:   proto.WorldEvent_SHOUT,
+		Source: &sender,
+		Text:   &msg,
+	}
+
+	// objects near anyone who can hear the shout get to react to it too
+	heard := map[int]bool{}
+	listeners := []db.Object{}
+	for _, uio := range s.connected() {
+		if ignoring[uint32(uio.avatar.OwnerID)] {
+			continue
+		}
+		uio.send(ev)
+
+		eshot, err := uio.avatar.Earshot(s.db)
+		if err != nil {
+			log.Printf("failed to find earshot of %d: %s", uio.avatar.ID, err.Error())
+			continue
+		}
+		for _, o := range eshot {
+			if !heard[o.ID] && !o.Avatar {
+				heard[o.ID] = true
+				listeners = append(listeners, *o)
+			}
+		}
+	}
+
+	for _, o := range listeners {
+		if err = s.verbHandler("shout", msg, avatar, o); err != nil {
+			log.Printf("error handling verb shout for object %d: %s", o.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *gameWorldServer) handleIgnore(avatar db.Object, cmd *proto.Command) error {
+	uid := uint32(avatar.OwnerID)
+	username := strings.TrimSpace(cmd.Rest)
+
+	if username == "" {
+		ignoring, err := s.db.Ignoring(uid)
+		if err != nil {
+			return err
+		}
+		if len(ignoring) == 0 {
+			s.printTo(avatar, "you aren't ignoring anyone.")
+			return nil
+		}
+		names := []string{}
+		for _, id := range ignoring {
+			if u, err := user.LookupId(strconv.Itoa(int(id))); err == nil {
+				names = append(names, u.Username)
+			}
+		}
+		s.printTo(avatar, "you are ignoring shouts from: "+strings.Join(names, ", "))
+		return nil
+	}
+
+	ignored, ok := lookupUID(username)
+	if !ok {
+		s.printTo(avatar, fmt.Sprintf("there is no one called %s.", username))
+		return nil
+	}
+
+	if ignored == uid {
+		s.printTo(avatar, "you can't ignore yourself.")
+		return nil
+	}
+
+	if err := s.db.Ignore(uid, ignored); err != nil {
+		return err
+	}
+
+	s.printTo(avatar, fmt.Sprintf("you will no longer hear shouts from %s. use /unignore %s to change your mind.",
+		username, username))
+
+	return nil
+}
+
+func (s *gameWorldServer) handleUnignore(avatar db.Object, cmd *proto.Command) error {
+	username := strings.TrimSpace(cmd.Rest)
+	if username == "" {
+		s.printTo(avatar, "usage: /unignore <user>")
+		return nil
+	}
+
+	ignored, ok := lookupUID(username)
+	if !ok {
+		s.printTo(avatar, fmt.Sprintf("there is no one called %s.", username))
+		return nil
+	}
+
+	was, err := s.db.Unignore(uint32(avatar.OwnerID), ignored)
+	if err != nil {
+		return err
+	}
+
+	if was {
+		s.printTo(avatar, fmt.Sprintf("you will hear shouts from %s again.", username))
+	} else {
+		s.printTo(avatar, fmt.Sprintf("you weren't ignoring %s.", username))
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"fmt"
+	"os/user"
+	"sort"
+	"time"
+
+	"github.com/vilmibm/hermeticum/proto"
+	"github.com/vilmibm/hermeticum/server/db"
+)
+
+type privilege int
+
+const (
+	privPlayer privilege = iota
+	// privStaff is root and members of ServeOpts.StaffGroup.
+	privStaff
+)
+
+// verb is something the server does itself rather than passing along to the
+// objects near the player. Verbs are registered from init functions so they
+// can live next to whatever else they need.
+type verb struct {
+	Name    string
+	Aliases []string
+	// Usage shows what goes after the verb, eg "<direction>".
+	Usage string
+	Help  string
+	// Privilege is who may use the verb.
+	Privilege privilege
+	// RateLimit is how long a player has to wait between uses. Zero means no
+	// limit.
+	RateLimit time.Duration
+	Handler   func(*gameWorldServer, db.Object, *proto.Command) error
+}
+
+// verbs maps names and aliases to their verb.
+var verbs = map[string]*verb{}
+
+func registerVerb(v *verb) {
+	for _, name := range append([]string{v.Name}, v.Aliases...) {
+		if _, ok := verbs[name]; ok {
+			panic(fmt.Sprintf("verb %s registered twice", name))
+		}
+		verbs[name] = v
+	}
+}
+
+// builtinVerbs returns every registered verb usable at priv, sorted by name.
+func builtinVerbs(priv privilege) []*verb {
+	out := []*verb{}
+	for name, v := range verbs {
+		if name == v.Name && v.Privilege <= priv {
+			out = append(out, v)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+
+	return out
+}
+
+// verbNames is builtinVerbs with aliases, for clients to complete with.
+func verbNames(priv privilege) []string {
+	out := []string{}
+	for _, v := range builtinVerbs(priv) {
+		out = append(out, v.Name)
+		out = append(out, v.Aliases...)
+	}
+
+	sort.Strings(out)
+
+	return out
+}
+
+func init() {
+	registerVerb(&verb{
+		Name:    "look",
+		Aliases: []string{"l"},
+		Help:    "describe the room you are in and what is in it.",
+		Handler: (*gameWorldServer).handleLook,
+	})
+	registerVerb(&verb{
+		Name:    "quit",
+		Help:    "leave hermeticum. next time you will arrive wherever you left.",
+		Handler: (*gameWorldServer).handleQuit,
+	})
+	registerVerb(&verb{
+		Name:      "dig",
+		Usage:     "<direction>",
+		Help:      "make a new room in direction with a door back to this one.",
+		RateLimit: 5 * time.Second,
+		Handler:   (*gameWorldServer).handleDig,
+	})
+	registerVerb(&verb{
+		Name:    "inv",
+		Aliases: []string{"i"},
+		Help:    "list what you are carrying.",
+		Handler: (*gameWorldServer).handleInv,
+	})
+	registerVerb(&verb{
+		Name:    "get",
+		Usage:   "<thing>",
+		Help:    "pick something up. use 2.thing or reply with a number when there are a few.",
+		Handler: (*gameWorldServer).handleGet,
+	})
+	registerVerb(&verb{
+		Name:    "drop",
+		Usage:   "<thing>",
+		Help:    "put something you are carrying down in this room.",
+		Handler: (*gameWorldServer).handleDrop,
+	})
+	registerVerb(&verb{
+		Name:      "create",
+		Help:      "conjure a new object into your pocket.",
+		RateLimit: 5 * time.Second,
+		Handler:   (*gameWorldServer).handleCreate,
+	})
+	registerVerb(&verb{
+		Name:    "home",
+		Help:    "go back to your home.",
+		Handler: (*gameWorldServer).handleHome,
+	})
+	registerVerb(&verb{
+		Name:    "sethome",
+		Help:    "make the room you are in your home.",
+		Handler: (*gameWorldServer).handleSetHome,
+	})
+}
+
+// privilegeFor works out what u is allowed to do.
+func privilegeFor(u *user.User, staffGroup string) privilege {
+	if u.Uid == "0" {
+		return privStaff
+	}
+
+	if staffGroup == "" {
+		return privPlayer
+	}
+
+	g, err := user.LookupGroup(staffGroup)
+	if err != nil {
+		return privPlayer
+	}
+
+	gids, err := u.GroupIds()
+	if err != nil {
+		return privPlayer
+	}
+
+	for _, gid := range gids {
+		if gid == g.Gid {
+			return privStaff
+		}
+	}
+
+	return privPlayer
+}
+
+// dispatch picks the handler for cmd: a registered verb if there is one and
+// the player may use it right now, otherwise the objects nearby. A verb the
+// player can't use gets a handler that says why. It is only called from the
+// session loop.
+func (s *gameWorldServer) dispatch(uio *userIO, cmd *proto.Command) func(db.Object, *proto.Command) error {
+	v, ok := verbs[cmd.Verb]
+	if !ok && scriptHooks[cmd.Verb] {
+		msg := fmt.Sprintf("/%s is something that happens, not something you can do.", cmd.Verb)
+		return func(avatar db.Object, _ *proto.Command) error {
+			s.printTo(avatar, msg)
+			return nil
+		}
+	}
+	if !ok {
+		return s.handleCmd
+	}
+
+	if v.Privilege > uio.privilege {
+		msg := fmt.Sprintf("only staff can /%s", cmd.Verb)
+		return func(avatar db.Object, _ *proto.Command) error {
+			s.printTo(avatar, msg)
+			return nil
+		}
+	}
+
+	if v.RateLimit > 0 {
+		if wait := v.RateLimit - time.Since(uio.lastUsed[v.Name]); wait > 0 {
+			msg := fmt.Sprintf("slow down. you can /%s again in %s.", cmd.Verb,
+				wait.Truncate(time.Second)+time.Second)
+			return func(avatar db.Object, _ *proto.Command) error {
+				s.printTo(avatar, msg)
+				return nil
+			}
+		}
+		uio.lastUsed[v.Name] = time.Now()
+	}
+
+	return func(avatar db.Object, cmd *proto.Command) error {
+		return v.Handler(s, avatar, cmd)
+	}
+}
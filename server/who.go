@@ -0,0 +1,159 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vilmibm/hermeticum/proto"
+	"github.com/vilmibm/hermeticum/server/db"
+)
+
+func init() {
+	registerVerb(&verb{
+		Name:    "who",
+		Help:    "list who is connected, how long they've been idle and where they are.",
+		Handler: (*gameWorldServer).handleWho,
+	})
+	registerVerb(&verb{
+		Name:    "where",
+		Usage:   "<user>",
+		Help:    "find out which room someone is in.",
+		Handler: (*gameWorldServer).handleWhere,
+	})
+	registerVerb(&verb{
+		Name:    "hide",
+		Help:    "toggle whether /who and /where tell people which room you are in.",
+		Handler: (*gameWorldServer).handleHide,
+	})
+}
+
+// connected returns a snapshot of every session, ordered by username.
+func (s *gameWorldServer) connected() []*userIO {
+	s.sessionMutex.Lock()
+	out := make([]*userIO, 0, len(s.sessions))
+	for _, uio := range s.sessions {
+		out = append(out, uio)
+	}
+	s.sessionMutex.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].username < out[j].username
+	})
+
+	return out
+}
+
+// broadcast sends ev to every session.
+func (s *gameWorldServer) broadcast(ev *proto.WorldEvent) {
+	for _, uio := range s.connected() {
+		uio.send(ev)
+	}
+}
+
+func (s *gameWorldServer) sendPresence(username string, online bool) {
+	s.broadcast(&proto.WorldEvent{
+		Type:   proto.WorldEvent_PRESENCE,
+		Source: &username,
+		Presence: &proto.Presence{
+			Name:   username,
+			Online: online,
+		},
+	})
+}
+
+// whereIs describes the room uio's avatar is in as seen by viewer, keeping it
+// private if uio has asked to hide.
+func (s *gameWorldServer) whereIs(uio *userIO, viewer db.Object) string {
+	self := uio.avatar.ID == viewer.ID
+	if uio.hidden.Load() && !self && s.privilegeOf(viewer) < privStaff {
+		return "somewhere private"
+	}
+
+	room, err := uio.avatar.Container(s.db)
+	if err != nil {
+		return "nowhere in particular"
+	}
+
+	where := room.String()
+	if uio.hidden.Load() {
+		where += " (hidden)"
+	}
+
+	return where
+}
+
+func (s *gameWorldServer) privilegeOf(avatar db.Object) privilege {
+	if uio, ok := s.session(uint32(avatar.OwnerID)); ok {
+		return uio.privilege
+	}
+
+	return privPlayer
+}
+
+// idleString rounds d to something readable like 42s, 5m or 3h.
+func idleString(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+
+	return fmt.Sprintf("%dh", int(d.Hours()))
+}
+
+func (s *gameWorldServer) handleWho(avatar db.Object, cmd *proto.Command) error {
+	sessions := s.connected()
+
+	msg := fmt.Sprintf("%d connected:", len(sessions))
+	for _, uio := range sessions {
+		msg += fmt.Sprintf("\n  %-16s idle %-4s %s",
+			uio.username, idleString(uio.idle()), s.whereIs(uio, avatar))
+	}
+
+	s.printTo(avatar, msg)
+
+	return nil
+}
+
+func (s *gameWorldServer) handleWhere(avatar db.Object, cmd *proto.Command) error {
+	name := strings.TrimSpace(cmd.Rest)
+	if name == "" {
+		s.printTo(avatar, "usage: /where <user>")
+		return nil
+	}
+
+	for _, uio := range s.connected() {
+		if strings.EqualFold(uio.username, name) {
+			s.printTo(avatar, fmt.Sprintf("%s is in %s.", uio.username, s.whereIs(uio, avatar)))
+			return nil
+		}
+	}
+
+	s.printTo(avatar, fmt.Sprintf("%s isn't connected right now.", name))
+
+	return nil
+}
+
+func (s *gameWorldServer) handleHide(avatar db.Object, cmd *proto.Command) error {
+	uio, ok := s.session(uint32(avatar.OwnerID))
+	if !ok {
+		return nil
+	}
+
+	hidden := !uio.hidden.Load()
+	if err := s.db.SetHidden(avatar, hidden); err != nil {
+		return err
+	}
+	uio.hidden.Store(hidden)
+
+	if hidden {
+		s.printTo(avatar, "your whereabouts are now private. /who and /where will show that you are connected but not where.")
+	} else {
+		s.printTo(avatar, "anyone can now see which room you are in with /who and /where.")
+	}
+
+	return nil
+}
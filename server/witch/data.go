@@ -0,0 +1,99 @@
+package witch
+
+import (
+	"fmt"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// maxDepth bounds how deeply nested a table fromLua will follow, so a table
+// that contains itself doesn't hang the server.
+const maxDepth = 20
+
+// toLua turns an object data value (see db.Object.Data) into a Lua value.
+func toLua(l *lua.LState, v any) lua.LValue {
+	switch v := v.(type) {
+	case nil:
+		return lua.LNil
+	case bool:
+		return lua.LBool(v)
+	case string:
+		return lua.LString(v)
+	case float64:
+		return lua.LNumber(v)
+	case int:
+		return lua.LNumber(v)
+	case int64:
+		return lua.LNumber(v)
+	case []any:
+		t := l.NewTable()
+		for _, e := range v {
+			t.Append(toLua(l, e))
+		}
+		return t
+	case map[string]any:
+		t := l.NewTable()
+		for k, e := range v {
+			t.RawSetString(k, toLua(l, e))
+		}
+		return t
+	}
+
+	return lua.LString(fmt.Sprint(v))
+}
+
+// fromLua turns a Lua value into something that can be kept in an object's
+// data. Tables with only the keys 1..n become lists and other tables become
+// maps with string keys. Functions and the like can't be kept and are an
+// error.
+func fromLua(v lua.LValue) (any, error) {
+	return fromLuaAt(v, 0)
+}
+
+func fromLuaAt(v lua.LValue, depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("tables nested more than %d deep", maxDepth)
+	}
+
+	switch v := v.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case lua.LBool:
+		return bool(v), nil
+	case lua.LString:
+		return string(v), nil
+	case lua.LNumber:
+		return float64(v), nil
+	case *lua.LTable:
+		n := v.MaxN()
+		count := 0
+		v.ForEach(func(lua.LValue, lua.LValue) { count++ })
+
+		if n > 0 && n == count {
+			out := make([]any, 0, n)
+			for ix := 1; ix <= n; ix++ {
+				e, err := fromLuaAt(v.RawGetInt(ix), depth+1)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, e)
+			}
+			return out, nil
+		}
+
+		out := map[string]any{}
+		var err error
+		v.ForEach(func(k, e lua.LValue) {
+			if err != nil {
+				return
+			}
+			var ge any
+			if ge, err = fromLuaAt(e, depth+1); err == nil {
+				out[k.String()] = ge
+			}
+		})
+		return out, err
+	}
+
+	return nil, fmt.Errorf("can't keep a %s", v.Type())
+}
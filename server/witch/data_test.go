@@ -0,0 +1,76 @@
+package witch
+
+import (
+	"reflect"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestDataRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		in   any
+		// want is what comes back, when that isn't in
+		want any
+	}{
+		{name: "nil", in: nil},
+		{name: "string", in: `a "quoted"\nstring`},
+		{name: "number", in: 1.5},
+		{name: "int", in: 3, want: 3.0},
+		{name: "bool", in: true},
+		{name: "list", in: []any{"a", 2.0, false}},
+		{name: "empty map", in: map[string]any{}},
+		{
+			name: "nested",
+			in: map[string]any{
+				"money": 0.0,
+				"stock": []any{map[string]any{"name": "cola", "price": 2.0}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := lua.NewState()
+			defer l.Close()
+
+			want := tt.want
+			if want == nil {
+				want = tt.in
+			}
+
+			got, err := fromLua(toLua(l, tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %#v, want %#v", got, want)
+			}
+		})
+	}
+}
+
+func TestFromLuaRefuses(t *testing.T) {
+	l := lua.NewState()
+	defer l.Close()
+
+	loop := l.NewTable()
+	loop.RawSetString("me", loop)
+
+	tests := []struct {
+		name string
+		in   lua.LValue
+	}{
+		{name: "function", in: l.NewFunction(func(*lua.LState) int { return 0 })},
+		{name: "table containing itself", in: loop},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := fromLua(tt.in); err == nil {
+				t.Errorf("kept %#v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package witch
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/vilmibm/hermeticum/server/db"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// objectRef reads argument n as a reference to an object: a proxy table, an
+// ID or a string like "vilmibm/gallery" (see DB.ObjectByRef).
+func (sc *ScriptContext) objectRef(l *lua.LState, n int) (*db.Object, error) {
+	return sc.resolve(l.Get(n))
+}
+
+func (sc *ScriptContext) resolve(ref lua.LValue) (*db.Object, error) {
+	switch v := ref.(type) {
+	case *lua.LTable:
+		return sc.db.ObjectByID(int(lua.LVAsNumber(v.RawGetString("ID"))))
+	case lua.LNumber:
+		return sc.db.ObjectByID(int(v))
+	case lua.LString:
+		return sc.db.ObjectByRef(string(v))
+	}
+
+	return nil, fmt.Errorf("expected an object, ID or owner/code, got %s", ref.Type())
+}
+
+// movable reports whether the running script may move obj. An object can
+// move itself, whatever it is holding and, while handling a verb the sender
+// ran, the sender.
+func (sc *ScriptContext) movable(me, obj *db.Object) bool {
+	if obj.ID == me.ID {
+		return true
+	}
+
+	if sc.running.Invoked && obj.ID == sc.running.Sender.ID {
+		return true
+	}
+
+	if c, err := obj.Container(sc.db); err == nil && c.ID == me.ID {
+		return true
+	}
+
+	return false
+}
+
+// reachable reports whether the running script may put things into dest. An
+// object can always put things in itself and the room it's in. Anywhere else
+// has to belong to the object's owner or let anyone carry things into it.
+func (sc *ScriptContext) reachable(me, dest *db.Object) bool {
+	if dest.ID == me.ID || dest.CarryableBy(me.OwnerID) {
+		return true
+	}
+
+	return sc.room(me).ID == dest.ID
+}
+
+// tryMove puts obj into dest if the running script is allowed to. The error
+// says why not, and is meant for the script.
+func (sc *ScriptContext) tryMove(obj, dest *db.Object, teleport bool) error {
+	me, err := sc.me()
+	if err != nil {
+		return err
+	}
+
+	if !sc.movable(me, obj) {
+		return fmt.Errorf("%s can't move %s", me.String(), obj.String())
+	}
+
+	// putting something inside itself, however indirectly, would lose it
+	// from the world
+	for o, ix := dest, 0; ix < maxNesting; ix++ {
+		if o.ID == obj.ID {
+			return fmt.Errorf("%s can't go inside itself", obj.String())
+		}
+		if o, err = o.Container(sc.db); err != nil {
+			break
+		}
+	}
+
+	if !sc.reachable(me, dest) {
+		return fmt.Errorf("%s doesn't let %s put things in it", dest.String(), me.String())
+	}
+
+	if ok, reason := sc.serverAPI.allowed(*me, *obj, *dest, sc.running.Hops); !ok {
+		return errors.New(reason)
+	}
+
+	do := sc.serverAPI.moveInto
+	if teleport {
+		do = sc.serverAPI.teleport
+	}
+
+	if err = do(*obj, *dest); err != nil {
+		log.Printf("failed to move %d into %d: %s", obj.ID, dest.ID, err.Error())
+		return errors.New("the move failed")
+	}
+
+	return nil
+}
+
+// move is tryMove for WITCH functions, returning true or false and a reason
+// to the script.
+func (sc *ScriptContext) move(l *lua.LState, obj *db.Object, dest *db.Object, teleport bool) int {
+	if err := sc.tryMove(obj, dest, teleport); err != nil {
+		l.Push(lua.LFalse)
+		l.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	l.Push(lua.LTrue)
+	return 1
+}
+
+// wMove is move(obj, dest).
+func (sc *ScriptContext) wMove(l *lua.LState) int {
+	obj, err := sc.objectRef(l, 1)
+	if err != nil {
+		l.Push(lua.LFalse)
+		l.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	dest, err := sc.objectRef(l, 2)
+	if err != nil {
+		l.Push(lua.LFalse)
+		l.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	return sc.move(l, obj, dest, false)
+}
+
+// wMoveSender walks the sender into dest.
+func (sc *ScriptContext) wMoveSender(l *lua.LState) int {
+	return sc.moveSender(l, false)
+}
+
+// wTeleportSender is wMoveSender with a puff of smoke.
+func (sc *ScriptContext) wTeleportSender(l *lua.LState) int {
+	return sc.moveSender(l, true)
+}
+
+func (sc *ScriptContext) moveSender(l *lua.LState, teleport bool) int {
+	sender, err := sc.sender()
+	if err != nil {
+		l.Push(lua.LFalse)
+		l.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	dest, err := sc.objectRef(l, 1)
+	if err != nil {
+		l.Push(lua.LFalse)
+		l.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	return sc.move(l, sender, dest, teleport)
+}
+
+// wCreate is create(name, code, data). The new object belongs to the owner of
+// the object whose script is running, has the same permissions and starts out
+// held by it. data, a table, is optional and becomes what the new object has.
+// It returns a proxy for the new object or nil and a reason.
+func (sc *ScriptContext) wCreate(l *lua.LState) int {
+	fail := func(reason string) int {
+		l.Push(lua.LNil)
+		l.Push(lua.LString(reason))
+		return 2
+	}
+
+	me, err := sc.me()
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	o := db.NewObject(uint32(me.OwnerID))
+	if data, ok := l.Get(3).(*lua.LTable); ok {
+		d, err := fromLua(data)
+		if err != nil {
+			return fail(err.Error())
+		}
+		m, ok := d.(map[string]any)
+		if !ok {
+			return fail("data should be a table of names to values")
+		}
+		for k, v := range m {
+			o.SetData(k, v)
+		}
+	}
+	o.SetData("name", l.CheckString(1))
+	o.SetScript(l.OptString(2, ""))
+	if me.Perms != nil {
+		perms := *me.Perms
+		o.Perms = &perms
+	}
+
+	if err = sc.serverAPI.create(o); err != nil {
+		log.Printf("script %d failed to create an object: %s", me.ID, err.Error())
+		return fail(err.Error())
+	}
+
+	if err = sc.serverAPI.moveInto(*o, *me); err != nil {
+		return fail(err.Error())
+	}
+
+	l.Push(proxy(l, *o, me.OwnerID))
+	return 1
+}
+
+// wDrop is drop(obj), which puts something the running object holds into the
+// room it is in.
+func (sc *ScriptContext) wDrop(l *lua.LState) int {
+	obj, err := sc.objectRef(l, 1)
+	if err != nil {
+		l.Push(lua.LFalse)
+		l.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	me, err := sc.me()
+	if err != nil {
+		l.Push(lua.LFalse)
+		l.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	return sc.move(l, obj, sc.room(me), false)
+}
@@ -0,0 +1,235 @@
+package witch
+
+/*
+
+WITCH functions for looking around. They hand back proxy tables: copies of an
+object's ID, name and (when the object allows the script's owner to read it)
+the rest of its data. Changing a proxy changes nothing in the world.
+
+*/
+
+import (
+	"errors"
+	"log"
+
+	"github.com/vilmibm/hermeticum/server/db"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// maxNesting stops room() walking up forever should containment ever loop.
+const maxNesting = 10
+
+func objectTable(l *lua.LState, o db.Object) *lua.LTable {
+	t := l.NewTable()
+	t.RawSetString("name", lua.LString(o.GetData("name")))
+	t.RawSetString("ID", lua.LNumber(o.ID))
+	return t
+}
+
+// proxy is objectTable plus everything in o's data that reader (a uid) is
+// allowed to read.
+func proxy(l *lua.LState, o db.Object, reader int) *lua.LTable {
+	t := objectTable(l, o)
+	t.RawSetString("avatar", lua.LBool(o.Avatar))
+	t.RawSetString("owner", lua.LNumber(o.OwnerID))
+
+	if !readable(o, reader) {
+		return t
+	}
+
+	for k, v := range o.Data {
+		if k == "ID" || k == "avatar" || k == "owner" {
+			continue
+		}
+		t.RawSetString(k, toLua(l, v))
+	}
+
+	return t
+}
+
+// readable is whether reader can see o's data. An object whose permissions
+// weren't loaded is only readable by its owner.
+func readable(o db.Object, reader int) bool {
+	return o.OwnerID == reader || (o.Perms != nil && o.Perms.Read == db.PermWorld)
+}
+
+func proxies(l *lua.LState, os []*db.Object, reader int) *lua.LTable {
+	t := l.NewTable()
+	for _, o := range os {
+		t.Append(proxy(l, *o, reader))
+	}
+	return t
+}
+
+// me is the object whose script is running. Every permission check starts
+// from it, so it comes from the object the state was loaded from and never
+// from a global a script could reassign.
+func (sc *ScriptContext) me() (*db.Object, error) {
+	if sc.loaded == nil {
+		return nil, errors.New("no script is loaded")
+	}
+
+	return sc.db.ObjectByID(sc.loaded.ID)
+}
+
+// objectArg is objectRef, except that no argument at all means me.
+func (sc *ScriptContext) objectArg(l *lua.LState, n int) (*db.Object, error) {
+	if l.Get(n) == lua.LNil {
+		return sc.me()
+	}
+
+	return sc.objectRef(l, n)
+}
+
+// room is the outermost container of o: the room it is in even if it's in
+// someone's pocket. A room is its own room.
+func (sc *ScriptContext) room(o *db.Object) *db.Object {
+	for ix := 0; ix < maxNesting; ix++ {
+		c, err := o.Container(sc.db)
+		if err != nil {
+			break
+		}
+		o = c
+	}
+
+	return o
+}
+
+func (sc *ScriptContext) wMe(l *lua.LState) int {
+	me, err := sc.me()
+	if err != nil {
+		log.Printf("me() failed: %s", err.Error())
+		l.Push(lua.LNil)
+		return 1
+	}
+
+	l.Push(proxy(l, *me, me.OwnerID))
+	return 1
+}
+
+func (sc *ScriptContext) wRoom(l *lua.LState) int {
+	me, err := sc.me()
+	if err != nil {
+		log.Printf("room() failed: %s", err.Error())
+		l.Push(lua.LNil)
+		return 1
+	}
+
+	l.Push(proxy(l, *sc.room(me), me.OwnerID))
+	return 1
+}
+
+// wContents lists what an object holds. Scripts can always see into their
+// own object and the room it is in; anything else needs Read permission.
+func (sc *ScriptContext) wContents(l *lua.LState) int {
+	me, err := sc.me()
+	if err != nil {
+		log.Printf("contents() failed: %s", err.Error())
+		l.Push(l.NewTable())
+		return 1
+	}
+
+	o, err := sc.objectArg(l, 1)
+	if err != nil {
+		log.Printf("contents() failed: %s", err.Error())
+		l.Push(l.NewTable())
+		return 1
+	}
+
+	if o.ID != me.ID && o.ID != sc.room(me).ID && !readable(*o, me.OwnerID) {
+		l.Push(l.NewTable())
+		return 1
+	}
+
+	os, err := o.Contents(sc.db)
+	if err != nil {
+		log.Printf("contents() failed: %s", err.Error())
+		l.Push(l.NewTable())
+		return 1
+	}
+
+	l.Push(proxies(l, os, me.OwnerID))
+	return 1
+}
+
+func (sc *ScriptContext) wContainer(l *lua.LState) int {
+	me, err := sc.me()
+	if err != nil {
+		log.Printf("container() failed: %s", err.Error())
+		l.Push(lua.LNil)
+		return 1
+	}
+
+	o, err := sc.objectArg(l, 1)
+	if err != nil {
+		log.Printf("container() failed: %s", err.Error())
+		l.Push(lua.LNil)
+		return 1
+	}
+
+	c, err := o.Container(sc.db)
+	if err != nil {
+		// not being anywhere is normal for rooms
+		l.Push(lua.LNil)
+		return 1
+	}
+
+	l.Push(proxy(l, *c, me.OwnerID))
+	return 1
+}
+
+// wNearby lists everything within earshot: the room and what is in it.
+func (sc *ScriptContext) wNearby(l *lua.LState) int {
+	me, err := sc.me()
+	if err != nil {
+		log.Printf("nearby() failed: %s", err.Error())
+		l.Push(l.NewTable())
+		return 1
+	}
+
+	os, err := me.Earshot(sc.db)
+	if err != nil {
+		log.Printf("nearby() failed: %s", err.Error())
+		l.Push(l.NewTable())
+		return 1
+	}
+
+	l.Push(proxies(l, os, me.OwnerID))
+	return 1
+}
+
+// wFind looks for something nearby or held by name, the same way players
+// refer to things ("orb", "2.orb", "me", "here"). It returns the first match
+// or nil.
+func (sc *ScriptContext) wFind(l *lua.LState) int {
+	me, err := sc.me()
+	if err != nil {
+		log.Printf("find() failed: %s", err.Error())
+		l.Push(lua.LNil)
+		return 1
+	}
+
+	candidates, err := me.Earshot(sc.db)
+	if err != nil {
+		log.Printf("find() failed: %s", err.Error())
+		l.Push(lua.LNil)
+		return 1
+	}
+
+	held, err := me.Contents(sc.db)
+	if err != nil {
+		log.Printf("find() failed: %s", err.Error())
+		l.Push(lua.LNil)
+		return 1
+	}
+	candidates = append(candidates, held...)
+
+	found, err := sc.db.Match(*me, candidates, l.ToString(1))
+	if err != nil || len(found) == 0 {
+		l.Push(lua.LNil)
+		return 1
+	}
+
+	l.Push(proxy(l, *found[0], me.OwnerID))
+	return 1
+}
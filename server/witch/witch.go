@@ -7,10 +7,16 @@ This file is the interface between the game server and WITCH execution
 */
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"reflect"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/vilmibm/hermeticum/proto"
 	"github.com/vilmibm/hermeticum/server/db"
@@ -30,15 +36,27 @@ allows({
   execute = "world",
 })
 
-hears(".*eat.*", function()
-  does("quivers nervously")
-end)
+hears(".*eat (?P<food>.*)", function(args)
+  does("quivers nervously at the thought of " .. args.captures.food)
+end, "mention food near it")
+
+hears("^hello", function(args)
+  does("waves")
+  return stop
+end, {help = "greet it", priority = 10})
 `
 */
 
 type serverAPI struct {
 	db         *db.DB
 	clientSend func(uint32, *proto.WorldEvent)
+	moveInto   func(obj, container db.Object) error
+	teleport   func(obj, container db.Object) error
+	create     func(o *db.Object) error
+	send       func(vc VerbContext) error
+	// allowed asks whatever might object whether mover's script can move obj
+	// into dest, and if not why.
+	allowed func(mover, obj, dest db.Object, hops int) (bool, string)
 }
 
 func (s *serverAPI) Tell(fromObjID, toObjID int, msg string) {
@@ -62,8 +80,8 @@ func (s *serverAPI) Tell(fromObjID, toObjID int, msg string) {
 	}
 
 	speakerName := "an ethereal presence"
-	if from.Data["name"] != "" {
-		speakerName = from.Data["name"]
+	if from.GetData("name") != "" {
+		speakerName = from.GetData("name")
 	}
 
 	ev := proto.WorldEvent{
@@ -94,8 +112,8 @@ func (s *serverAPI) Show(fromObjID, toObjID int, action string) {
 	}
 
 	speakerName := "an ethereal presence"
-	if from.Data["name"] != "" {
-		speakerName = from.Data["name"]
+	if from.GetData("name") != "" {
+		speakerName = from.GetData("name")
 	}
 
 	ev := proto.WorldEvent{
@@ -115,172 +133,567 @@ type VerbContext struct {
 	Rest   string
 	Sender db.Object
 	Target db.Object
+	// Invoked is set when Sender did Verb themselves, as opposed to having it
+	// happen to them (like arriving somewhere).
+	Invoked bool
+	// Hops counts how many scripts passed this verb along with send.
+	Hops int
+}
+
+// maxHops stops objects sending verbs back and forth forever.
+const maxHops = 8
+
+// VerbHelp describes one handler a script has registered.
+type VerbHelp struct {
+	Verb    string
+	Pattern string
+	// Help is the optional last argument given to hears, sees, provides and
+	// friends.
+	Help string
+}
+
+// scriptQuery runs a function on the script goroutine once target's script
+// is loaded. Verbs and the server's questions for a script are all
+// scriptQueries, run one at a time in the order they were queued.
+type scriptQuery struct {
+	target db.Object
+	run    func(*lua.LState)
+}
+
+// Action is a builtin action that before handlers get a say in.
+type Action struct {
+	// Name is get, drop, go or create.
+	Name   string
+	Rest   string
+	Sender db.Object
+	// Object is what is being acted on, if anything, like the thing being
+	// picked up.
+	Object *db.Object
+	// Hops is how many sends led to the action, when a script's move is
+	// what caused it. Anything before handlers send carries on from there.
+	Hops int
+}
+
+// Verdict is what a script's before handlers decided about an Action.
+type Verdict struct {
+	Denied bool
+	// Reason is what to tell the sender when Denied.
+	Reason string
+	// Rest is the Action's Rest, possibly rewritten.
+	Rest string
 }
 
 type ScriptContext struct {
 	db         *db.DB
 	clientSend func(uint32, *proto.WorldEvent)
-	script     string
-	incoming   chan VerbContext
-	serverAPI  serverAPI
+	// loaded is the object the lua state was last built from
+	loaded    *db.Object
+	serverAPI serverAPI
+	// queue is what is waiting for the script goroutine, oldest first.
+	queue      []scriptQuery
+	queueMutex sync.Mutex
+	// wake has something in it whenever queue might not be empty.
+	wake chan struct{}
+	// before is the loaded script's before handlers by action. It is only
+	// touched on the script goroutine, and is kept here rather than in a
+	// global so that scripts can't break it.
+	before map[string][]*lua.LFunction
+	// running is the verb, or the Action, that the script goroutine is
+	// running handlers for. Who may be moved and how many sends deep the
+	// script is depend on it, so it is kept here rather than in globals
+	// that scripts could reassign.
+	running VerbContext
 }
 
-func NewScriptContext(db *db.DB, clientSend func(uint32, *proto.WorldEvent)) (*ScriptContext, error) {
+// maxQueued is how many verbs can wait for a script before more are dropped.
+const maxQueued = 256
+
+func NewScriptContext(db *db.DB, clientSend func(uint32, *proto.WorldEvent), moveInto, teleport func(obj, container db.Object) error, create func(o *db.Object) error, send func(vc VerbContext) error, allowed func(mover, obj, dest db.Object, hops int) (bool, string)) (*ScriptContext, error) {
 	sc := &ScriptContext{
-		serverAPI: serverAPI{db: db, clientSend: clientSend},
+		serverAPI: serverAPI{db: db, clientSend: clientSend, moveInto: moveInto, teleport: teleport, create: create, send: send, allowed: allowed},
 		db:        db,
 	}
-	sc.incoming = make(chan VerbContext)
+	sc.wake = make(chan struct{}, 1)
 
 	go func() {
 		var l *lua.LState
-		var err error
-		var vc VerbContext
-		for {
-			vc = <-sc.incoming
-			if vc.Target.GetScript() != sc.script {
-				sc.script = vc.Target.GetScript()
-				l = lua.NewState()
-
-				// direction constants
-				l.SetGlobal("east", lua.LString(dirEast))
-				l.SetGlobal("west", lua.LString(dirWest))
-				l.SetGlobal("north", lua.LString(dirNorth))
-				l.SetGlobal("south", lua.LString(dirSouth))
-				l.SetGlobal("above", lua.LString(dirAbove))
-				l.SetGlobal("below", lua.LString(dirBelow))
-				l.SetGlobal("up", lua.LString(dirAbove))
-				l.SetGlobal("down", lua.LString(dirBelow))
-
-				// witch object behavior functions
-				l.SetGlobal("allows", l.NewFunction(sc.wAllows))
-				l.SetGlobal("has", l.NewFunction(sc.wHas))
-				l.SetGlobal("hears", l.NewFunction(sc.wHears))
-				l.SetGlobal("sees", l.NewFunction(sc.wSees))
-				l.SetGlobal("goes", l.NewFunction(sc.wGoes))
-				l.SetGlobal("seen", l.NewFunction(sc.wSeen))
-				l.SetGlobal("my", l.NewFunction(sc.wMy))
-				l.SetGlobal("provides", l.NewFunction(sc.wProvides))
-
-				// witch helpers
-				l.SetGlobal("_handlers", l.NewTable())
-				l.SetGlobal("_ID", lua.LNumber(vc.Target.ID))
-
-				if err := l.DoString(vc.Target.GetScript()); err != nil {
-					log.Printf("error parsing script %s: %s", vc.Target.GetScript(), err.Error())
+
+		for range sc.wake {
+			for {
+				q, ok := sc.next()
+				if !ok {
+					break
 				}
+				sc.running = VerbContext{}
+				l = sc.load(l, q.target)
+				q.run(l)
 			}
+		}
+	}()
 
-			// witch action functions relative to calling context
-
-			l.SetGlobal("tellMe", l.NewFunction(func(l *lua.LState) int {
-				sender := l.GetGlobal("sender").(*lua.LTable)
-				senderID := int(lua.LVAsNumber(sender.RawGetString("ID")))
-
-				log.Printf("tellMe: %d %s", senderID, l.ToString(1))
-				sc.serverAPI.Tell(senderID, vc.Target.ID, l.ToString(1))
-				return 0
-			}))
-
-			l.SetGlobal("tellSender", l.NewFunction(func(l *lua.LState) int {
-				sender := l.GetGlobal("sender").(*lua.LTable)
-				senderID := int(lua.LVAsNumber(sender.RawGetString("ID")))
-
-				log.Printf("tellMe: %d %s", senderID, l.ToString(1))
-				sc.serverAPI.Tell(vc.Target.ID, senderID, l.ToString(1))
-				return 0
-			}))
-
-			// TODO commenting this out until I decide on UX for object short coding
-			/*
-				l.SetGlobal("moveSender", l.NewFunction(func(l *lua.LState) (ret int) {
-					ret = 0
-					sender := l.GetGlobal("sender").(*lua.LTable)
-					senderID := int(lua.LVAsNumber(sender.RawGetString("ID")))
-					owner := l.ToString(1)
-					name := l.ToString(2)
-					db := sc.db
-					senderObj, err := db.GetObjectByID(senderID)
-					if err != nil {
-						log.Println(err.Error())
-						return
-					}
-					container, err := db.GetObject(owner, name)
-					if err != nil {
-						log.Println(err.Error())
-						return
-					}
-					if err = senderObj.MoveInto(db, *container); err != nil {
-						log.Println(err.Error())
-					}
+	return sc, nil
+}
 
-					return
-				}))
-			*/
-
-			l.SetGlobal("showMe", l.NewFunction(func(l *lua.LState) int {
-				sender := l.GetGlobal("sender").(*lua.LTable)
-				senderID := int(lua.LVAsNumber(sender.RawGetString("ID")))
-
-				log.Printf("showMe: %d %s", senderID, l.ToString(1))
-				sc.serverAPI.Show(senderID, vc.Target.ID, l.ToString(1))
-				return 0
-			}))
-
-			// TODO showSender?
-
-			// TODO check execute permission and bail out potentially
-			//log.Printf("%#v", vc)
-
-			senderT := l.NewTable()
-			senderT.RawSetString("name", lua.LString(vc.Sender.Data["name"]))
-			senderT.RawSetString("ID", lua.LNumber(vc.Sender.ID))
-			l.SetGlobal("sender", senderT)
-			l.SetGlobal("msg", lua.LString(vc.Rest))
-			l.SetGlobal("_SENDERID", lua.LNumber(vc.Sender.ID))
-
-			handlers := l.GetGlobal("_handlers").(*lua.LTable)
-			handlers.ForEach(func(k, v lua.LValue) {
-				log.Println("checking handler verbs", k)
-				if k.String() != vc.Verb {
+// enqueue adds q to the work waiting for the script goroutine. If limit is
+// more than zero and that much is already waiting q is dropped and enqueue
+// returns false.
+func (sc *ScriptContext) enqueue(q scriptQuery, limit int) bool {
+	sc.queueMutex.Lock()
+	if limit > 0 && len(sc.queue) >= limit {
+		sc.queueMutex.Unlock()
+		return false
+	}
+	sc.queue = append(sc.queue, q)
+	sc.queueMutex.Unlock()
+
+	select {
+	case sc.wake <- struct{}{}:
+	default:
+	}
+
+	return true
+}
+
+// next takes the oldest thing waiting in the queue, if there is anything.
+func (sc *ScriptContext) next() (scriptQuery, bool) {
+	sc.queueMutex.Lock()
+	defer sc.queueMutex.Unlock()
+
+	if len(sc.queue) == 0 {
+		return scriptQuery{}, false
+	}
+	q := sc.queue[0]
+	sc.queue[0] = scriptQuery{}
+	sc.queue = sc.queue[1:]
+
+	return q, true
+}
+
+// run runs vc's handlers in l, which has vc.Target's script loaded.
+func (sc *ScriptContext) run(l *lua.LState, vc VerbContext) {
+	// witch action functions relative to calling context
+
+	l.SetGlobal("tellMe", l.NewFunction(func(l *lua.LState) int {
+		sender := l.GetGlobal("sender").(*lua.LTable)
+		senderID := int(lua.LVAsNumber(sender.RawGetString("ID")))
+
+		log.Printf("tellMe: %d %s", senderID, l.ToString(1))
+		sc.serverAPI.Tell(senderID, vc.Target.ID, l.ToString(1))
+		return 0
+	}))
+
+	l.SetGlobal("tellSender", l.NewFunction(func(l *lua.LState) int {
+		sender := l.GetGlobal("sender").(*lua.LTable)
+		senderID := int(lua.LVAsNumber(sender.RawGetString("ID")))
+
+		log.Printf("tellMe: %d %s", senderID, l.ToString(1))
+		sc.serverAPI.Tell(vc.Target.ID, senderID, l.ToString(1))
+		return 0
+	}))
+
+	l.SetGlobal("showMe", l.NewFunction(func(l *lua.LState) int {
+		sender := l.GetGlobal("sender").(*lua.LTable)
+		senderID := int(lua.LVAsNumber(sender.RawGetString("ID")))
+
+		log.Printf("showMe: %d %s", senderID, l.ToString(1))
+		sc.serverAPI.Show(senderID, vc.Target.ID, l.ToString(1))
+		return 0
+	}))
+
+	// TODO showSender?
+
+	// TODO check execute permission and bail out potentially
+	//log.Printf("%#v", vc)
+
+	senderT := l.NewTable()
+	senderT.RawSetString("name", lua.LString(vc.Sender.GetData("name")))
+	senderT.RawSetString("ID", lua.LNumber(vc.Sender.ID))
+	l.SetGlobal("sender", senderT)
+	l.SetGlobal("msg", lua.LString(vc.Rest))
+	sc.running = vc
+	sc.dispatch(l, vc)
+}
+
+// load is l, or a new state if o's script, data or permissions have changed
+// since l was made.
+func (sc *ScriptContext) load(l *lua.LState, o db.Object) *lua.LState {
+	if l != nil && sc.loaded != nil && sc.loaded.GetScript() == o.GetScript() &&
+		reflect.DeepEqual(sc.loaded.Data, o.Data) && reflect.DeepEqual(sc.loaded.Perms, o.Perms) {
+		return l
+	}
+	if l != nil {
+		l.Close()
+	}
+	sc.loaded = &o
+
+	return sc.newState(o)
+}
+
+// newState is a fresh lua state with the WITCH functions defined and o's
+// script run.
+func (sc *ScriptContext) newState(o db.Object) *lua.LState {
+	l := lua.NewState()
+	sc.before = map[string][]*lua.LFunction{}
+
+	// direction constants
+	l.SetGlobal("east", lua.LString(dirEast))
+	l.SetGlobal("west", lua.LString(dirWest))
+	l.SetGlobal("north", lua.LString(dirNorth))
+	l.SetGlobal("south", lua.LString(dirSouth))
+	l.SetGlobal("above", lua.LString(dirAbove))
+	l.SetGlobal("below", lua.LString(dirBelow))
+	l.SetGlobal("up", lua.LString(dirAbove))
+	l.SetGlobal("down", lua.LString(dirBelow))
+
+	// witch object behavior functions
+	l.SetGlobal("allows", l.NewFunction(sc.wAllows))
+	l.SetGlobal("has", l.NewFunction(sc.wHas))
+	l.SetGlobal("hears", l.NewFunction(sc.wHears))
+	l.SetGlobal("hearsShout", l.NewFunction(sc.wHearsShout))
+	l.SetGlobal("sees", l.NewFunction(sc.wSees))
+	l.SetGlobal("goes", l.NewFunction(sc.wGoes))
+	l.SetGlobal("seen", l.NewFunction(sc.wSeen))
+	l.SetGlobal("my", l.NewFunction(sc.wMy))
+	l.SetGlobal("set", l.NewFunction(sc.wSet))
+	l.SetGlobal("provides", l.NewFunction(sc.wProvides))
+	l.SetGlobal("arrives", l.NewFunction(sc.wArrives))
+	l.SetGlobal("departs", l.NewFunction(sc.wDeparts))
+	l.SetGlobal("before", l.NewFunction(sc.wBefore))
+	l.SetGlobal("deny", l.NewFunction(sc.wDeny))
+	l.SetGlobal("rewrite", l.NewFunction(sc.wRewrite))
+
+	// witch query functions
+	l.SetGlobal("me", l.NewFunction(sc.wMe))
+	l.SetGlobal("room", l.NewFunction(sc.wRoom))
+	l.SetGlobal("contents", l.NewFunction(sc.wContents))
+	l.SetGlobal("container", l.NewFunction(sc.wContainer))
+	l.SetGlobal("nearby", l.NewFunction(sc.wNearby))
+	l.SetGlobal("find", l.NewFunction(sc.wFind))
+
+	// witch movement functions
+	l.SetGlobal("move", l.NewFunction(sc.wMove))
+	l.SetGlobal("moveSender", l.NewFunction(sc.wMoveSender))
+	l.SetGlobal("teleportSender", l.NewFunction(sc.wTeleportSender))
+	l.SetGlobal("create", l.NewFunction(sc.wCreate))
+	l.SetGlobal("drop", l.NewFunction(sc.wDrop))
+	l.SetGlobal("send", l.NewFunction(sc.wSend))
+
+	// witch helpers
+	l.SetGlobal("_handlers", l.NewTable())
+	stop := l.NewTable()
+	l.SetGlobal("stop", stop)
+	l.SetGlobal("_STOP", stop)
+
+	// what the object has and allows are handed over as values, never
+	// as source, so nothing in them can be run. a script calling has()
+	// or allows() itself replaces them.
+	l.SetGlobal("_has", toLua(l, o.Data))
+	allows := l.NewTable()
+	if o.Perms != nil {
+		allows.RawSetString("read", lua.LString(o.Perms.Read))
+		allows.RawSetString("write", lua.LString(o.Perms.Write))
+		allows.RawSetString("carry", lua.LString(o.Perms.Carry))
+		allows.RawSetString("execute", lua.LString(o.Perms.Exec))
+	}
+	l.SetGlobal("_allows", allows)
+
+	if err := l.DoString(o.GetScript()); err != nil {
+		log.Printf("error parsing script for %d: %s", o.ID, err.Error())
+	}
+
+	return l
+}
+
+// verbEvents are the kinds of WorldEvent the verbs scripts hear correspond
+// to.
+var verbEvents = map[string]proto.WorldEvent_WorldEventType{
+	"say":     proto.WorldEvent_OVERHEARD,
+	"emote":   proto.WorldEvent_EMOTE,
+	"shout":   proto.WorldEvent_SHOUT,
+	"whisper": proto.WorldEvent_WHISPER,
+	"arrives": proto.WorldEvent_ENTER,
+	"departs": proto.WorldEvent_LEAVE,
+}
+
+// handlerArgs is the table handlers are called with:
+//
+//	args.verb      the verb, like "say"
+//	args.rest      everything after the verb
+//	args.sender    a proxy (see proxy) for whoever did it
+//	args.captures  what pattern's groups matched, by number and by name
+//	args.event     the kind of WorldEvent the verb is, like "OVERHEARD", if
+//	               it is one
+func handlerArgs(l *lua.LState, vc VerbContext, pattern *regexp.Regexp, match []string) *lua.LTable {
+	args := l.NewTable()
+	args.RawSetString("verb", lua.LString(vc.Verb))
+	args.RawSetString("rest", lua.LString(vc.Rest))
+	args.RawSetString("sender", proxy(l, vc.Sender, vc.Target.OwnerID))
+
+	captures := l.NewTable()
+	for ix, name := range pattern.SubexpNames() {
+		if ix == 0 {
+			continue
+		}
+		captures.RawSetInt(ix, lua.LString(match[ix]))
+		if name != "" {
+			captures.RawSetString(name, lua.LString(match[ix]))
+		}
+	}
+	args.RawSetString("captures", captures)
+
+	if ev, ok := verbEvents[vc.Verb]; ok {
+		args.RawSetString("event", lua.LString(ev.String()))
+	}
+
+	return args
+}
+
+// Handle queues vc for target's script and returns without waiting for it to
+// run. Verbs handed to one ScriptContext run in the order they were handed
+// over.
+func (sc *ScriptContext) Handle(vc VerbContext) {
+	q := scriptQuery{target: vc.Target, run: func(l *lua.LState) {
+		sc.run(l, vc)
+	}}
+	if !sc.enqueue(q, maxQueued) {
+		log.Printf("dropped %s for %d: too many verbs waiting", vc.Verb, vc.Target.ID)
+	}
+}
+
+// Verbs lists the handlers target's script registers. target should be the
+// object this ScriptContext runs scripts for.
+func (sc *ScriptContext) Verbs(target db.Object) []VerbHelp {
+	var out []VerbHelp
+	sc.query(target, func(l *lua.LState) {
+		out = sc.verbHelp(l)
+	})
+
+	return out
+}
+
+// beforeTimeout is how long target's script gets to decide about an Action,
+// waiting its turn included.
+var beforeTimeout = 2 * time.Second
+
+// Before runs target's before handlers for a and reports what they decided.
+// A script that doesn't decide within beforeTimeout, say because it is busy
+// or stuck, denies a. Before must not be called from target's own script.
+func (sc *ScriptContext) Before(target db.Object, a Action) Verdict {
+	ctx, cancel := context.WithTimeout(context.Background(), beforeTimeout)
+	defer cancel()
+
+	v := Verdict{Rest: a.Rest}
+	answered := sc.queryWithin(ctx, target, func(l *lua.LState) {
+		l.SetContext(ctx)
+		defer l.RemoveContext()
+
+		// the action hasn't happened yet, so it isn't Invoked and nobody
+		// can be moved on the back of it
+		sc.running = VerbContext{Verb: a.Name, Rest: a.Rest, Sender: a.Sender, Target: target, Hops: a.Hops}
+
+		for _, fn := range sc.before[a.Name] {
+			args := l.NewTable()
+			args.RawSetString("action", lua.LString(a.Name))
+			args.RawSetString("rest", lua.LString(v.Rest))
+			args.RawSetString("sender", objectTable(l, a.Sender))
+			if a.Object != nil {
+				args.RawSetString("object", objectTable(l, *a.Object))
+			}
+
+			if err := l.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, args); err != nil {
+				log.Printf("error in before %s handler for %d: %s", a.Name, target.ID, err.Error())
+				if ctx.Err() != nil {
+					v.Denied = true
 					return
 				}
-				v.(*lua.LTable).ForEach(func(kk, vv lua.LValue) {
-					pattern := regexp.MustCompile(kk.String())
-					log.Println("checking handler", kk.String(), vv, pattern)
-					if pattern.MatchString(vc.Rest) {
-						// TODO TODO TODO TODO TODO
-						// this could be a remote code execution vuln; but by being here, I
-						// believe vc.Verb has been effectively validated as "not a pile of
-						// lua code" since it matched a handler.
-						if err = l.DoString(fmt.Sprintf(`_handlers.%s["%s"]()`, vc.Verb, pattern)); err != nil {
-							log.Println(err.Error())
-						}
-					}
-				})
+				continue
+			}
+			ret, ok := l.Get(-1).(*lua.LTable)
+			l.Pop(1)
+			if !ok {
+				continue
+			}
+
+			if lua.LVAsBool(ret.RawGetString("denied")) {
+				v.Denied = true
+				v.Reason = lua.LVAsString(ret.RawGetString("reason"))
+				return
+			}
+			if rest, ok := ret.RawGetString("rewrite").(lua.LString); ok {
+				v.Rest = string(rest)
+			}
+		}
+	})
+
+	if !answered || ctx.Err() != nil {
+		log.Printf("%d took too long to decide about %s", target.ID, a.Name)
+		return Verdict{
+			Denied: true,
+			Reason: fmt.Sprintf("%s is taking too long to decide. try again in a moment.", target.GetData("name")),
+			Rest:   a.Rest,
+		}
+	}
+
+	return v
+}
+
+// query runs fn on the script goroutine with target's script loaded and
+// waits for it to finish.
+func (sc *ScriptContext) query(target db.Object, fn func(*lua.LState)) {
+	sc.queryWithin(context.Background(), target, fn)
+}
+
+// queryWithin is query, except that if ctx is done before fn gets its turn
+// fn is skipped and queryWithin returns false. Once fn has started it is
+// waited for; fn should watch ctx itself if it could run long.
+func (sc *ScriptContext) queryWithin(ctx context.Context, target db.Object, fn func(*lua.LState)) bool {
+	// started is 1 once fn is running, 2 if the caller gave up first
+	var started atomic.Int32
+	done := make(chan struct{})
+	sc.enqueue(scriptQuery{target: target, run: func(l *lua.LState) {
+		if !started.CompareAndSwap(0, 1) {
+			return
+		}
+		fn(l)
+		close(done)
+	}}, 0)
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		if started.CompareAndSwap(0, 2) {
+			return false
+		}
+		<-done
+		return true
+	}
+}
+
+// handlersFor is the list of handlers registered for verb. _handlers is an
+// ordinary global that a script can overwrite, so its shape is checked rather
+// than assumed; the handlers in the list still need checking too.
+func handlersFor(l *lua.LState, verb string) (*lua.LTable, bool) {
+	handlers, ok := l.GetGlobal("_handlers").(*lua.LTable)
+	if !ok {
+		return nil, false
+	}
+	list, ok := handlers.RawGetString(verb).(*lua.LTable)
+	return list, ok
+}
+
+func (sc *ScriptContext) verbHelp(l *lua.LState) []VerbHelp {
+	out := []VerbHelp{}
+	handlers, ok := l.GetGlobal("_handlers").(*lua.LTable)
+	if !ok {
+		return out
+	}
+	handlers.ForEach(func(verb, _ lua.LValue) {
+		list, ok := handlersFor(l, verb.String())
+		if !ok {
+			return
+		}
+		for ix := 1; ix <= list.Len(); ix++ {
+			h, ok := list.RawGetInt(ix).(*lua.LTable)
+			if !ok {
+				continue
+			}
+			out = append(out, VerbHelp{
+				Verb:    verb.String(),
+				Pattern: lua.LVAsString(h.RawGetString("pattern")),
+				Help:    lua.LVAsString(h.RawGetString("help")),
 			})
 		}
-	}()
+	})
 
-	return sc, nil
+	// handlers for one verb stay in the order they run
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Verb < out[j].Verb
+	})
+
+	return out
 }
 
-func (sc *ScriptContext) Handle(vc VerbContext) {
-	sc.incoming <- vc
+// dispatch runs vc.Verb's handlers whose patterns match vc.Rest, in order,
+// until one returns stop.
+func (sc *ScriptContext) dispatch(l *lua.LState, vc VerbContext) {
+	list, ok := handlersFor(l, vc.Verb)
+	if !ok {
+		return
+	}
+
+	for ix := 1; ix <= list.Len(); ix++ {
+		h, ok := list.RawGetInt(ix).(*lua.LTable)
+		if !ok {
+			log.Printf("skipping a handler for %s in %d that isn't a table", vc.Verb, vc.Target.ID)
+			continue
+		}
+		pattern, err := regexp.Compile(lua.LVAsString(h.RawGetString("pattern")))
+		if err != nil {
+			log.Printf("bad pattern for %s in %d: %s", vc.Verb, vc.Target.ID, err.Error())
+			continue
+		}
+		match := pattern.FindStringSubmatch(vc.Rest)
+		if match == nil {
+			continue
+		}
+
+		args := handlerArgs(l, vc, pattern, match)
+		if err = l.CallByParam(lua.P{Fn: h.RawGetString("fn"), NRet: 1, Protect: true}, args); err != nil {
+			log.Printf("error in %s handler for %d: %s", vc.Verb, vc.Target.ID, err.Error())
+			continue
+		}
+		ret := l.Get(-1)
+		l.Pop(1)
+		if ret == l.GetGlobal("_STOP") {
+			return
+		}
+	}
 }
 
-func (sc *ScriptContext) addHandler(l *lua.LState, verb, pattern string, cb *lua.LFunction) {
+// addHandler registers cb to be called, with handlerArgs, when verb happens
+// and what follows it matches pattern. opts is the optional last argument
+// the WITCH function was given: either help text or a table like
+// {help = "...", priority = 10}. Handlers with a higher priority run first;
+// otherwise they run in the order they were added.
+func (sc *ScriptContext) addHandler(l *lua.LState, verb, pattern string, cb *lua.LFunction, opts lua.LValue) {
 	log.Printf("adding handler: %s %s %#v", verb, string(pattern), cb)
 
-	handlers := l.GetGlobal("_handlers").(*lua.LTable)
+	h := l.NewTable()
+	h.RawSetString("pattern", lua.LString(pattern))
+	h.RawSetString("fn", cb)
+
+	priority := 0
+	switch opts := opts.(type) {
+	case lua.LString:
+		h.RawSetString("help", opts)
+	case *lua.LTable:
+		h.RawSetString("help", lua.LString(lua.LVAsString(opts.RawGetString("help"))))
+		priority = int(lua.LVAsNumber(opts.RawGetString("priority")))
+	}
+	h.RawSetString("priority", lua.LNumber(priority))
 
-	verbHandlers, ok := handlers.RawGetString(verb).(*lua.LTable)
+	list, ok := handlersFor(l, verb)
 	if !ok {
-		verbHandlers = l.NewTable()
-		handlers.RawSetString(verb, verbHandlers)
+		handlers, ok := l.GetGlobal("_handlers").(*lua.LTable)
+		if !ok {
+			handlers = l.NewTable()
+			l.SetGlobal("_handlers", handlers)
+		}
+		list = l.NewTable()
+		handlers.RawSetString(verb, list)
 	}
 
-	verbHandlers.RawSetString(pattern, cb)
+	// after everything of the same or higher priority
+	at := list.Len() + 1
+	for ix := 1; ix <= list.Len(); ix++ {
+		other, ok := list.RawGetInt(ix).(*lua.LTable)
+		if ok && int(lua.LVAsNumber(other.RawGetString("priority"))) < priority {
+			at = ix
+			break
+		}
+	}
+	list.Insert(at, h)
 }
 
 func (sc *ScriptContext) wMy(l *lua.LState) int {
@@ -290,6 +703,53 @@ func (sc *ScriptContext) wMy(l *lua.LState) int {
 	return 1
 }
 
+// wSet is set(key, value). It changes what the running object has for good:
+// my(key), and anyone looking at the object, sees value from then on. A nil
+// value removes key. It returns true, or false and a reason.
+func (sc *ScriptContext) wSet(l *lua.LState) int {
+	fail := func(reason string) int {
+		l.Push(lua.LFalse)
+		l.Push(lua.LString(reason))
+		return 2
+	}
+
+	key := l.CheckString(1)
+	v, err := fromLua(l.Get(2))
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	me, err := sc.me()
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	if v == nil {
+		delete(me.Data, key)
+	} else {
+		me.SetData(key, v)
+	}
+	if err = me.SaveData(sc.db); err != nil {
+		log.Printf("failed to save data for %d: %s", me.ID, err.Error())
+		return fail("couldn't save that")
+	}
+
+	if hasT, ok := l.GetGlobal("_has").(*lua.LTable); ok {
+		hasT.RawSetString(key, toLua(l, v))
+	}
+
+	// the state already knows about the change, so it needn't be rebuilt
+	// when the object next turns up with it
+	if sc.loaded != nil {
+		loaded := *sc.loaded
+		loaded.Data = me.Data
+		sc.loaded = &loaded
+	}
+
+	l.Push(lua.LTrue)
+	return 1
+}
+
 func (sc *ScriptContext) wAllows(l *lua.LState) int {
 	l.SetGlobal("_allows", l.ToTable(1))
 	// TODO
@@ -305,7 +765,16 @@ func (sc *ScriptContext) wHas(l *lua.LState) int {
 func (sc *ScriptContext) wHears(l *lua.LState) int {
 	pattern := l.ToString(1)
 	cb := l.ToFunction(2)
-	sc.addHandler(l, "say", pattern, cb)
+	sc.addHandler(l, "say", pattern, cb, l.Get(3))
+	return 0
+}
+
+// wHearsShout is hears for /shout, which reaches objects anywhere near a
+// connected user.
+func (sc *ScriptContext) wHearsShout(l *lua.LState) int {
+	pattern := l.ToString(1)
+	cb := l.ToFunction(2)
+	sc.addHandler(l, "shout", pattern, cb, l.Get(3))
 	return 0
 }
 
@@ -313,16 +782,100 @@ func (sc *ScriptContext) wSees(l *lua.LState) int {
 	pattern := l.ToString(1)
 	cb := l.ToFunction(2)
 
-	sc.addHandler(l, "emote", pattern, cb)
+	sc.addHandler(l, "emote", pattern, cb, l.Get(3))
 	return 0
 }
 
 func (sc *ScriptContext) wSeen(l *lua.LState) int {
 	cb := l.ToFunction(1)
-	sc.addHandler(l, "look", ".*", cb)
+	sc.addHandler(l, "look", ".*", cb, l.Get(2))
+	return 0
+}
+
+// wArrives calls back whenever something (the sender) arrives where this
+// object can hear it: in the same room, or in this object if it is a room.
+func (sc *ScriptContext) wArrives(l *lua.LState) int {
+	cb := l.ToFunction(1)
+	sc.addHandler(l, "arrives", ".*", cb, l.Get(2))
+	return 0
+}
+
+// wDeparts is wArrives for things leaving.
+func (sc *ScriptContext) wDeparts(l *lua.LState) int {
+	cb := l.ToFunction(1)
+	sc.addHandler(l, "departs", ".*", cb, l.Get(2))
+	return 0
+}
+
+// wBefore registers a handler that runs before a builtin action (get, drop,
+// go or create) happens nearby. It gets a table describing the action and can
+// return deny(reason) to stop it or rewrite(rest) to change its argument.
+func (sc *ScriptContext) wBefore(l *lua.LState) int {
+	action := l.CheckString(1)
+	cb := l.CheckFunction(2)
+
+	sc.before[action] = append(sc.before[action], cb)
+
 	return 0
 }
 
+func (sc *ScriptContext) wDeny(l *lua.LState) int {
+	t := l.NewTable()
+	t.RawSetString("denied", lua.LTrue)
+	t.RawSetString("reason", lua.LString(l.OptString(1, "")))
+	l.Push(t)
+	return 1
+}
+
+func (sc *ScriptContext) wRewrite(l *lua.LState) int {
+	t := l.NewTable()
+	t.RawSetString("rewrite", lua.LString(l.ToString(1)))
+	l.Push(t)
+	return 1
+}
+
+// wSend is send(target, verb, rest). It hands verb straight to target's
+// script as though this object had done it, wherever target is. It returns
+// true, or false and a reason. Delivery happens in the background.
+func (sc *ScriptContext) wSend(l *lua.LState) int {
+	fail := func(reason string) int {
+		l.Push(lua.LFalse)
+		l.Push(lua.LString(reason))
+		return 2
+	}
+
+	hops := sc.running.Hops
+	if hops >= maxHops {
+		return fail(fmt.Sprintf("this verb has already been passed along %d times", hops))
+	}
+
+	target, err := sc.objectRef(l, 1)
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	me, err := sc.me()
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	// the verb isn't Invoked: target is being told something by me, not
+	// having me do something in front of it, so it can't move me
+	err = sc.serverAPI.send(VerbContext{
+		Verb:   l.CheckString(2),
+		Rest:   l.OptString(3, ""),
+		Sender: *me,
+		Target: *target,
+		Hops:   hops + 1,
+	})
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	l.Push(lua.LTrue)
+	return 1
+}
+
 func (sc *ScriptContext) wDoes(ls *lua.LState) int {
 	// TODO how to feed events back into the server?
 	// it needs to behave like an event showing up in Commands stream
@@ -340,18 +893,20 @@ func (sc *ScriptContext) wProvides(l *lua.LState) int {
 	verb := split[0]
 	pattern := split[1]
 
-	sc.addHandler(l, verb, pattern, cb)
+	sc.addHandler(l, verb, pattern, cb, l.Get(3))
 	return 0
 }
 
 func (sc *ScriptContext) wGoes(l *lua.LState) int {
 	direction := newDirection(l.ToString(1))
-	targetRoomID := l.ToInt(2)
+	// looked up each time so a door can be written before the room it leads
+	// to has been given its code
+	target := l.Get(2)
 
 	log.Printf("GOT DIRECTION %v", direction)
 
 	cb := func(l *lua.LState) (ret int) {
-		targetRoom, err := sc.db.GetObjectByID(targetRoomID)
+		targetRoom, err := sc.resolve(target)
 		if err != nil {
 			log.Printf("failed to find room %s", err.Error())
 			return
@@ -363,27 +918,40 @@ func (sc *ScriptContext) wGoes(l *lua.LState) int {
 		}
 		normalized := NormalizeDirection(msg)
 
-		sender, err := sc.getSenderFromState(l)
+		sender, err := sc.sender()
 		if err != nil {
 			log.Printf("failed to find sender %s", err.Error())
 			return
 		}
 
 		if normalized.Equals(direction) {
-			log.Printf("MOVING SENDER TO '%s'", targetRoom.Data["name"])
-			// TODO error checking
-			sender.MoveInto(sc.db, *targetRoom)
-			sc.serverAPI.Tell(targetRoom.ID, sender.ID, fmt.Sprintf("you are now in %s", targetRoom.Data["name"]))
+			log.Printf("MOVING SENDER TO '%s'", targetRoom.GetData("name"))
+			// the same checks as move(): only a sender who went this way
+			// themselves can be taken, and before("go") gets a say
+			if err = sc.tryMove(sender, targetRoom, false); err != nil {
+				log.Printf("failed to move sender %d: %s", sender.ID, err.Error())
+				if sc.running.Invoked {
+					sc.serverAPI.Tell(sc.loaded.ID, sender.ID, err.Error())
+				}
+				return
+			}
+			sc.serverAPI.Tell(targetRoom.ID, sender.ID, fmt.Sprintf("you are now in %s", targetRoom.GetData("name")))
+			// the sender has gone; no other exit should take them too
+			l.Push(l.GetGlobal("_STOP"))
+			return 1
 		}
 		return
 	}
 
-	sc.addHandler(l, "go", ".*", l.NewFunction(cb))
+	opts := l.Get(3)
+	if opts == lua.LNil {
+		opts = lua.LString(fmt.Sprintf("leads %s", direction.Human()))
+	}
+	sc.addHandler(l, "go", ".*", l.NewFunction(cb), opts)
 	return 0
 }
 
-func (sc *ScriptContext) getSenderFromState(l *lua.LState) (*db.Object, error) {
-	lsID := lua.LVAsNumber(l.GetGlobal("_SENDERID"))
-
-	return sc.db.GetObjectByID(int(lsID))
+// sender is whoever did the verb or Action being handled.
+func (sc *ScriptContext) sender() (*db.Object, error) {
+	return sc.db.GetObjectByID(sc.running.Sender.ID)
 }
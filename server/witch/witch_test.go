@@ -0,0 +1,500 @@
+package witch
+
+import (
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vilmibm/hermeticum/server/db"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// dispatchScript counts the handlers that run and sets pwned should anything
+// smuggled in through a name, verb or message get run instead.
+const dispatchScript = `
+pwned = false
+calls = 0
+function pwn() pwned = true end
+
+hears(".*", function()
+	calls = calls + 1
+end)
+
+provides(my("verb") .. " .*", function()
+	calls = calls + 1
+end)
+`
+
+func FuzzDispatch(f *testing.F) {
+	f.Add("orb", "poke", "the orb")
+	f.Add(`"] pwn() --`, `x"] pwn() --`, `"]() pwn() --`)
+	f.Add("a \"quoted\"\nname", "say", "hello\x00there")
+	f.Add("]]--[[", "_handlers", "pwn()")
+	f.Add("x = 1 }) pwn() has({", "say", ".*")
+
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	// the script is reloaded whenever the object's data changes, so one
+	// context does for every input
+	sc, err := NewScriptContext(nil, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Fuzz(func(t *testing.T, name, verb, rest string) {
+		if verb == "" || strings.ContainsAny(verb, " \t\n") {
+			t.Skip("provides() can't register verbs containing spaces")
+		}
+
+		o := db.NewObject(1000)
+		o.ID = 1
+		o.SetData("name", name)
+		o.SetData("verb", verb)
+		o.SetScript(dispatchScript)
+
+		sender := db.NewAvatar(1001, name)
+		sender.ID = 2
+
+		// the state is kept when the object hasn't changed since last time
+		sc.query(*o, func(l *lua.LState) {
+			l.SetGlobal("calls", lua.LNumber(0))
+		})
+		sc.Handle(VerbContext{Verb: verb, Rest: rest, Sender: *sender, Target: *o})
+
+		sc.query(*o, func(l *lua.LState) {
+			if lua.LVAsBool(l.GetGlobal("pwned")) {
+				t.Errorf("injected code ran for name %q verb %q rest %q", name, verb, rest)
+				return
+			}
+
+			// the provides handler always matches. so does hears, when the
+			// verb is say.
+			want := 1
+			if verb == "say" {
+				want = 2
+			}
+			if calls := int(lua.LVAsNumber(l.GetGlobal("calls"))); calls != want {
+				t.Errorf("ran %d handlers for %q, want %d", calls, verb, want)
+			}
+
+			has := l.GetGlobal("_has").(*lua.LTable)
+			if got := lua.LVAsString(has.RawGetString("name")); got != name {
+				t.Errorf("name came through as %q, want %q", got, name)
+			}
+		})
+	})
+}
+
+func TestHandleOrder(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	sc, err := NewScriptContext(nil, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := db.NewObject(1000)
+	o.ID = 1
+	o.SetScript(`
+heard = ""
+hears(".*", function(args)
+	heard = heard .. args.rest
+end)`)
+	sender := db.NewAvatar(1001, "vilmibm")
+	sender.ID = 2
+
+	// nothing is waiting on the script yet, so none of these should block
+	want := ""
+	for ix := 0; ix < 100; ix++ {
+		rest := strconv.Itoa(ix) + " "
+		want += rest
+		sc.Handle(VerbContext{Verb: "say", Rest: rest, Sender: *sender, Target: *o})
+	}
+
+	sc.query(*o, func(l *lua.LState) {
+		if got := lua.LVAsString(l.GetGlobal("heard")); got != want {
+			t.Errorf("heard %q, want %q", got, want)
+		}
+	})
+}
+
+func TestRunningResets(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	sc, err := NewScriptContext(nil, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := db.NewObject(1000)
+	o.ID = 1
+	o.SetScript(`hears(".*", function(args) end)`)
+	sender := db.NewAvatar(1001, "vilmibm")
+	sender.ID = 2
+
+	var during VerbContext
+	sc.Handle(VerbContext{Verb: "say", Rest: "hi", Sender: *sender, Target: *o, Invoked: true})
+	sc.query(*o, func(l *lua.LState) {
+		during = sc.running
+	})
+
+	// whatever runs next mustn't be able to move the last verb's sender
+	if during.Invoked || during.Sender.ID != 0 {
+		t.Errorf("running carried over from the last verb: %+v", during)
+	}
+}
+
+func TestBeforeTimeout(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	defer func(d time.Duration) { beforeTimeout = d }(beforeTimeout)
+	beforeTimeout = 50 * time.Millisecond
+
+	sender := db.NewAvatar(1001, "vilmibm")
+	sender.ID = 2
+
+	tests := []struct {
+		name   string
+		script string
+		// busy hands the script a verb first, which keeps it busy for a
+		// while
+		busy       bool
+		wantDenied bool
+		wantRest   string
+	}{
+		{
+			name:     "answers in time",
+			script:   `before("go", function(args) return rewrite("north") end)`,
+			wantRest: "north",
+		},
+		{
+			name:       "runaway handler",
+			script:     `before("go", function(args) while true do end end)`,
+			wantDenied: true,
+			wantRest:   "south",
+		},
+		{
+			name: "busy script",
+			script: `
+before("go", function(args) return rewrite("north") end)
+hears(".*", function(args)
+	local start = os.clock()
+	while os.clock() - start < 0.5 do end
+end)`,
+			busy:       true,
+			wantDenied: true,
+			wantRest:   "south",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := NewScriptContext(nil, nil, nil, nil, nil, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			o := db.NewObject(1000)
+			o.ID = 1
+			o.SetData("name", "door")
+			o.SetScript(tt.script)
+
+			if tt.busy {
+				sc.Handle(VerbContext{Verb: "say", Rest: "hi", Sender: *sender, Target: *o})
+			}
+
+			start := time.Now()
+			v := sc.Before(*o, Action{Name: "go", Rest: "south", Sender: *sender})
+			if took := time.Since(start); took > 10*beforeTimeout {
+				t.Errorf("Before took %s", took)
+			}
+			if v.Denied != tt.wantDenied || v.Rest != tt.wantRest {
+				t.Errorf("got %+v, want denied %v and rest %q", v, tt.wantDenied, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestReadable(t *testing.T) {
+	world := &db.Permissions{Read: db.PermWorld}
+	owner := &db.Permissions{Read: db.PermOwner}
+
+	tests := []struct {
+		name   string
+		perms  *db.Permissions
+		reader int
+		want   bool
+	}{
+		{name: "owner", perms: owner, reader: 1000, want: true},
+		{name: "world readable", perms: world, reader: 1001, want: true},
+		{name: "owner only", perms: owner, reader: 1001, want: false},
+		{name: "no perms loaded", perms: nil, reader: 1001, want: false},
+		{name: "no perms loaded, owner", perms: nil, reader: 1000, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := db.NewObject(1000)
+			o.Perms = tt.perms
+			if got := readable(*o, tt.reader); got != tt.want {
+				t.Errorf("readable = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBeforeHops(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	sc, err := NewScriptContext(nil, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := db.NewObject(1000)
+	o.ID = 1
+	o.SetScript(`
+before("go", function(args)
+	_HOPS = 0
+	local ok, why = send(1, "poke")
+	refused = why
+end)`)
+	sender := db.NewAvatar(1001, "vilmibm")
+	sender.ID = 2
+
+	// a move some chain of sends led to can't be used to start the chain
+	// over, even by a script that resets what used to hold the count
+	sc.Before(*o, Action{Name: "go", Rest: "north", Sender: *sender, Hops: maxHops})
+
+	sc.query(*o, func(l *lua.LState) {
+		if got := lua.LVAsString(l.GetGlobal("refused")); !strings.Contains(got, "passed along") {
+			t.Errorf("send from a before handler wasn't refused, got %q", got)
+		}
+	})
+}
+
+func TestBeforeClobbered(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	sc, err := NewScriptContext(nil, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := db.NewObject(1000)
+	o.ID = 1
+	o.SetData("name", "door")
+	o.SetScript(`
+before("go", function(args) return deny("it's shut") end)
+_before = 5`)
+	sender := db.NewAvatar(1001, "vilmibm")
+	sender.ID = 2
+
+	v := sc.Before(*o, Action{Name: "go", Rest: "north", Sender: *sender})
+	if !v.Denied || v.Reason != "it's shut" {
+		t.Errorf("got %+v, want the handler's denial", v)
+	}
+}
+
+// scripted is an object running script, and someone to send it verbs.
+func scripted(script string) (o, sender *db.Object) {
+	o = db.NewObject(1000)
+	o.ID = 1
+	o.SetScript(script)
+
+	sender = db.NewAvatar(1001, "vilmibm")
+	sender.ID = 2
+
+	return o, sender
+}
+
+func TestHandlerArgs(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	tests := []struct {
+		name    string
+		pattern string
+		rest    string
+		// want is captures by number (as "1", "2", ...) and by name
+		want map[string]string
+	}{
+		{
+			name:    "numbered",
+			pattern: `^(\w+) (\w+)$`,
+			rest:    "hello there",
+			want:    map[string]string{"1": "hello", "2": "there"},
+		},
+		{
+			name:    "named",
+			pattern: `eat (?P<food>\w+)`,
+			rest:    "i could eat cake",
+			want:    map[string]string{"1": "cake", "food": "cake"},
+		},
+		{
+			name:    "named and numbered",
+			pattern: `^(\w+) gives (?P<gift>\w+)$`,
+			rest:    "alice gives flowers",
+			want:    map[string]string{"1": "alice", "2": "flowers", "gift": "flowers"},
+		},
+		{
+			name:    "group that didn't match",
+			pattern: `^a(b)?$`,
+			rest:    "a",
+			want:    map[string]string{"1": ""},
+		},
+		{
+			name:    "no groups",
+			pattern: `.*`,
+			rest:    "anything",
+			want:    map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := NewScriptContext(nil, nil, nil, nil, nil, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			o, sender := scripted(`
+hears(my("pattern"), function(args)
+	got = args
+end)`)
+			o.SetData("pattern", tt.pattern)
+
+			sc.Handle(VerbContext{Verb: "say", Rest: tt.rest, Sender: *sender, Target: *o, Invoked: true})
+
+			sc.query(*o, func(l *lua.LState) {
+				args, ok := l.GetGlobal("got").(*lua.LTable)
+				if !ok {
+					t.Error("handler didn't run")
+					return
+				}
+
+				for k, want := range map[string]string{
+					"verb":  "say",
+					"rest":  tt.rest,
+					"event": "OVERHEARD",
+				} {
+					if got := lua.LVAsString(args.RawGetString(k)); got != want {
+						t.Errorf("args.%s is %q, want %q", k, got, want)
+					}
+				}
+				s, _ := args.RawGetString("sender").(*lua.LTable)
+				if s == nil || lua.LVAsString(s.RawGetString("name")) != "vilmibm" {
+					t.Errorf("args.sender isn't vilmibm")
+				}
+
+				got := map[string]string{}
+				captures := args.RawGetString("captures").(*lua.LTable)
+				captures.ForEach(func(k, v lua.LValue) {
+					got[k.String()] = lua.LVAsString(v)
+				})
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("captures are %v, want %v", got, tt.want)
+				}
+			})
+		})
+	}
+}
+
+func TestDispatchOrder(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	tests := []struct {
+		name   string
+		script string
+		rest   string
+		want   string
+	}{
+		{
+			name: "registration order",
+			script: `
+hears(".*", function() ran = ran .. "a" end)
+hears(".*", function() ran = ran .. "b" end)`,
+			want: "ab",
+		},
+		{
+			name: "priority first",
+			script: `
+hears(".*", function() ran = ran .. "a" end)
+hears(".*", function() ran = ran .. "b" end, {priority = 10})`,
+			want: "ba",
+		},
+		{
+			name: "equal priorities keep their order",
+			script: `
+hears(".*", function() ran = ran .. "a" end)
+hears(".*", function() ran = ran .. "b" end, {priority = 5})
+hears(".*", function() ran = ran .. "c" end, {help = "c", priority = 5})
+hears(".*", function() ran = ran .. "d" end, {priority = -1})`,
+			want: "bcad",
+		},
+		{
+			name: "stop",
+			script: `
+hears(".*", function() ran = ran .. "a" return stop end)
+hears(".*", function() ran = ran .. "b" end)`,
+			want: "a",
+		},
+		{
+			name: "stop only counts when the handler ran",
+			script: `
+hears("^x", function() ran = ran .. "a" return stop end, {priority = 1})
+hears(".*", function() ran = ran .. "b" end)`,
+			rest: "y",
+			want: "b",
+		},
+		{
+			name: "returning anything else carries on",
+			script: `
+hears(".*", function() ran = ran .. "a" return {} end)
+hears(".*", function() ran = ran .. "b" end)`,
+			want: "ab",
+		},
+		{
+			name: "junk in the list is skipped",
+			script: `
+hears(".*", function() ran = ran .. "a" end)
+table.insert(_handlers.say, 1, "junk")
+hears(".*", function() ran = ran .. "b" end)`,
+			want: "ab",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := NewScriptContext(nil, nil, nil, nil, nil, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			o, sender := scripted(`ran = ""` + tt.script)
+
+			sc.Handle(VerbContext{Verb: "say", Rest: tt.rest, Sender: *sender, Target: *o, Invoked: true})
+
+			sc.query(*o, func(l *lua.LState) {
+				if got := lua.LVAsString(l.GetGlobal("ran")); got != tt.want {
+					t.Errorf("ran %q, want %q", got, tt.want)
+				}
+			})
+
+			// listing them for /help has to cope with junk as well
+			sc.Verbs(*o)
+		})
+	}
+}